@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// autoplayCmd() lets a strategy play in the terminal, and returns the exit status.
+// It plays with its own money: the profile and the saved session aren't touched.
+func autoplayCmd(args []string) int {
+	fs := flag.NewFlagSet("autoplay", flag.ExitOnError)
+	strategy := fs.String("strategy", "ev", "how to play: "+strings.Join(tower.StrategyNames, ", "))
+	rounds := fs.Int("rounds", 10, "rounds to play, 0 plays until the money runs out")
+	seed := fs.Int64("seed", 0, "seed for every shuffle, and for the random strategy (default random)")
+	balance := fs.Int("balance", tower.StartingBalance, "money to start with")
+	bet := fs.Int("bet", tower.WagerStep, "bet to start with, a multiple of 15")
+	maxBet := fs.Int("max-bet", tower.DefaultMaxWager, "table maximum bet, a multiple of 15")
+	deckName := fs.String("deck", tower.DiamondDeck.Name, "deck to deal from: "+deckNames())
+	rows := fs.Int("rows", tower.DefaultRows, fmt.Sprintf("tower height, gate row included (%d-%d)", tower.MinRows, tower.MaxRows))
+	counts := fs.Bool("counts", false, "let the strategy see how many of each card are left")
+	delay := fs.Duration("delay", time.Second/2, "pause between moves")
+	colorFlag := fs.String("color", "auto", "color cards: auto, always or never")
+	fs.Parse(args)
+
+	color, err := colorMode(*colorFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	deck, err := tower.DeckByName(*deckName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	s, err := tower.NewStrategy(*strategy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	sum := &summary{}
+	var scr *screen // the header needs the seed, so the screen comes after the game
+	opts := []tower.Option{tower.WithMaxWager(*maxBet), tower.WithDeck(deck), tower.WithRows(*rows), tower.WithBalance(*balance),
+		tower.WithColor(color), tower.WithRoundEnd(sum.add), tower.WithRoundEnd(func(r tower.Result) { printResult(scr, r) })}
+	if flagSet(fs, "seed") {
+		opts = append(opts, tower.WithSeed(*seed))
+	}
+	g, err := tower.NewGame(opts...)
+	if err == nil {
+		err = g.SetWager(*bet)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	sum.startBalance = g.Balance()
+
+	scr = newScreen(os.Stdout, fmt.Sprintf("Strategy: %s, seed: %d\n", *strategy, g.Seed()))
+	g.SetOutput(scr)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	code := autoplay(&g, s, tower.StrategyRand(g.Seed()), *counts, scr, sigs, *rounds, *delay)
+	fmt.Print(sum.report(g.Balance()))
+	return code
+}
+
+// autoplay() plays rounds by s, drawing every move, until it has played rounds rounds (all of
+// them if rounds is 0), the money runs out or a signal arrives. It returns the exit status:
+// 0 when it stops by itself, 1 if s fails or makes a move that isn't allowed, and 128 + the signal number
+// for a signal. rng and counts are passed on to s.
+func autoplay(g *tower.Game, s tower.Strategy, rng *rand.Rand, counts bool, scr *screen, sigs <-chan os.Signal, rounds int, delay time.Duration) int {
+	// show each decision as it's made, before the round's result
+	shown := tower.StrategyFunc(func(v tower.View, rng *rand.Rand) (tower.Action, error) {
+		a, err := s.Decide(v, rng)
+		if err == nil {
+			fmt.Fprintf(scr, "Strategy: %s\n", a)
+		}
+		return a, err
+	})
+
+	for played := 0; rounds == 0 || played < rounds; {
+		before := g.State()
+		_, err := g.Step(shown, rng, counts)
+		if errors.Is(err, tower.ErrInsufficientBalance) {
+			fmt.Fprintln(scr, "Out of money.")
+			scr.flush()
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintln(scr, err)
+			scr.flush()
+			return 1
+		}
+		if g.State() == tower.StateBetting && before != tower.StateBetting {
+			played++
+		} else {
+			g.PrintTower()
+		}
+		fmt.Fprintf(scr, "Money: %d\n", g.Balance())
+		scr.flush()
+
+		select {
+		case sig := <-sigs:
+			fmt.Fprintln(scr.out)
+			if s, ok := sig.(syscall.Signal); ok {
+				return 128 + int(s)
+			}
+			return 1
+		case <-time.After(delay):
+		}
+	}
+	return 0
+}
+
+// printResult() tells w how a round went.
+func printResult(w io.Writer, r tower.Result) {
+	switch {
+	case r.Bust:
+		fmt.Fprintf(w, "Bust on row %d\n", r.Row)
+	case r.Jackpot:
+		fmt.Fprintf(w, "Jackpot! Paid %d\n", r.Payout)
+	default:
+		fmt.Fprintf(w, "Paid %d for row %d\n", r.Payout, r.Row)
+	}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// defaultBotTimeout is how long a bot has to answer, unless --bot-timeout says otherwise.
+const defaultBotTimeout = 5 * time.Second
+
+// maxBadAnswers is how many answers in a row a bot can get wrong before it's given up on.
+const maxBadAnswers = 3
+
+var (
+	errBadAnswer = errors.New("bad answer")
+	errBotQuit   = errors.New("bot quit")
+)
+
+// botMessage is what a bot is sent whenever it has a decision to make: what it can see, what just
+// happened, and why its last answer was turned down, if it was.
+//
+// Event is "start" before the first round, "deal" after a row is dealt, "gate" when the gate card
+// was played into it, "bust" and "complete" when the round is over and waiting to be collected,
+// "round_end" once it's been cashed out or collected, and "error" if the last answer was turned
+// down. Result is sent once, with the first message after a round's result is settled.
+type botMessage struct {
+	Event string `json:"event"`
+	tower.View
+	Result *tower.Result `json:"result,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// botAnswer is what a bot answers with: "bet" with the wager, "hit", "cash_out" or "quit".
+type botAnswer struct {
+	Action string `json:"action"`
+	Wager  int    `json:"wager,omitempty"`
+}
+
+// action() returns the answer as a tower.Action.
+func (a botAnswer) action() (tower.Action, error) {
+	if a.Action == "quit" {
+		return tower.Action{}, errBotQuit
+	}
+	var k tower.ActionKind
+	err := k.UnmarshalText([]byte(a.Action))
+	return tower.Action{Kind: k, Wager: a.Wager}, err
+}
+
+// bot is a player that's another program, talking over its stdin and stdout in lines of JSON:
+// it's sent a botMessage whenever it has a decision to make, and answers with a botAnswer.
+// A bot that doesn't answer in time, stops answering or gets maxBadAnswers answers wrong in
+// a row is stopped, and every message after that fails. Its stdin is closed when the game is
+// done with it.
+type bot struct {
+	cmd        *exec.Cmd
+	in         io.WriteCloser
+	lines      chan []byte
+	readErr    error         // why lines was closed
+	done       chan struct{} // closed when the bot is stopped or closed, so nothing waits on lines
+	timeout    time.Duration
+	transcript io.Writer // every line sent and received, if not nil
+	err        error     // why the bot was stopped
+	playing    bool      // whether the last message was sent during a round
+}
+
+// startBot() starts command, split on spaces, as a bot. Its stderr goes to ours.
+// It's given timeout to answer each message, and the conversation is written to transcript
+// if it isn't nil: lines sent start with "> ", lines received with "< " and notes with "! ".
+func startBot(command string, timeout time.Duration, transcript io.Writer) (*bot, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("bot: no command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("bot: %w", err)
+	}
+
+	b := &bot{cmd: cmd, in: in, lines: make(chan []byte), done: make(chan struct{}), timeout: timeout, transcript: transcript}
+	go func() {
+		r := bufio.NewReader(out)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				b.readErr = err
+				close(b.lines)
+				return
+			}
+			select {
+			case b.lines <- line:
+			case <-b.done:
+				return
+			}
+		}
+	}()
+	return b, nil
+}
+
+// Decide() asks the bot what to do, so it can play as a tower.Strategy. Games played this way
+// collect finished rounds without asking, so the bot never sees "bust" or "complete".
+func (b *bot) Decide(v tower.View, _ *rand.Rand) (tower.Action, error) {
+	event := "deal"
+	switch {
+	case v.State == tower.StateBetting && b.playing:
+		event = "round_end"
+	case v.State == tower.StateBetting:
+		event = "start"
+	case v.GateRow == v.Row:
+		event = "gate"
+	}
+	b.playing = v.State != tower.StateBetting
+
+	a, err := b.ask(botMessage{Event: event, View: v})
+	if err != nil {
+		return tower.Action{}, err
+	}
+	return a.action()
+}
+
+// ask() sends the bot msg and returns its answer. Answers that can't be read are turned down,
+// and the bot is sent msg again, with the reason, up to maxBadAnswers times.
+func (b *bot) ask(msg botMessage) (botAnswer, error) {
+	if b.err != nil {
+		return botAnswer{}, b.err
+	}
+	for bad := 1; ; bad++ {
+		a, err := b.exchange(msg)
+		if err == nil {
+			return a, nil
+		}
+		if !errors.Is(err, errBadAnswer) {
+			return botAnswer{}, b.stop(err)
+		}
+		if bad == maxBadAnswers {
+			return botAnswer{}, b.stop(fmt.Errorf("bot gave %d bad answers in a row, the last: %w", bad, err))
+		}
+		b.note(err.Error())
+		msg.Event, msg.Error = "error", err.Error()
+	}
+}
+
+// exchange() sends msg and reads one answer.
+func (b *bot) exchange(msg botMessage) (botAnswer, error) {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return botAnswer{}, err
+	}
+	b.log("> ", line)
+	if _, err := b.in.Write(append(line, '\n')); err != nil {
+		return botAnswer{}, fmt.Errorf("bot: %w", err)
+	}
+
+	select {
+	case line, ok := <-b.lines:
+		if !ok {
+			return botAnswer{}, fmt.Errorf("bot stopped answering: %w", b.readErr)
+		}
+		line = bytes.TrimSpace(line)
+		b.log("< ", line)
+		var a botAnswer
+		if err := json.Unmarshal(line, &a); err != nil {
+			return botAnswer{}, fmt.Errorf("%w: can't read %q: %v", errBadAnswer, line, err)
+		}
+		if _, err := a.action(); err != nil && err != errBotQuit {
+			return botAnswer{}, fmt.Errorf("%w: %q, want an action of bet, hit, cash_out or quit", errBadAnswer, line)
+		}
+		return a, nil
+	case <-time.After(b.timeout):
+		return botAnswer{}, fmt.Errorf("bot didn't answer within %s", b.timeout)
+	}
+}
+
+// stop() gives up on the bot because of err, which it returns.
+func (b *bot) stop(err error) error {
+	b.err = err
+	b.note(err.Error())
+	close(b.done)
+	b.cmd.Process.Kill()
+	return err
+}
+
+// log() adds a line to the transcript.
+func (b *bot) log(prefix string, line []byte) {
+	if b.transcript != nil {
+		fmt.Fprintf(b.transcript, "%s%s\n", prefix, line)
+	}
+}
+
+// note() adds a note to the transcript.
+func (b *bot) note(s string) {
+	b.log("! ", []byte(s))
+}
+
+// close() closes the bot's stdin and waits for it to exit, stopping it if it takes longer than its timeout.
+func (b *bot) close() error {
+	b.in.Close()
+	if b.err == nil {
+		close(b.done)
+	}
+	done := make(chan error, 1)
+	go func() { done <- b.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(b.timeout):
+		b.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+// botTable lets a bot play a game in place of the keyboard: its answers are pressed as keys,
+// through Input(), the same as a player's, and it's sent the game after every one.
+type botTable struct {
+	b      *bot
+	counts bool          // let the bot see the unseen cards
+	delay  time.Duration // pause between moves, to watch the game
+	ended  *tower.Result // the round that ended since the last message
+}
+
+// roundEnd() is the game's round end hook, so the bot can be told how the round went.
+func (t *botTable) roundEnd(r tower.Result) {
+	t.ended = &r
+}
+
+// play() runs the game until the bot quits or stops, or a signal arrives, and returns the exit
+// status: 0 if the bot quits or exits, 1 if it fails, and 128 + the signal number for a signal.
+// Answers the game turns down count as bad answers. If autosave isn't nil it's called after every move.
+func (t *botTable) play(g *tower.Game, scr *screen, sigs <-chan os.Signal, autosave func() error) int {
+	event, errText, rejected := "start", "", 0
+	if g.State() != tower.StateBetting {
+		event = tableEvent(g) // resumed mid-round
+	}
+	for {
+		if g.State() != tower.StateBetting {
+			g.PrintTower()
+		}
+		if t.ended != nil {
+			printResult(scr, *t.ended)
+		}
+		if errText != "" {
+			fmt.Fprintln(scr, errText)
+		}
+		fmt.Fprintf(scr, "Money: %d\n", g.Balance())
+		scr.flush()
+
+		select {
+		case sig := <-sigs:
+			fmt.Fprintln(scr.out)
+			if s, ok := sig.(syscall.Signal); ok {
+				return 128 + int(s)
+			}
+			return 1
+		case <-time.After(t.delay):
+		}
+
+		a, err := t.b.ask(botMessage{Event: event, View: g.View(t.counts), Result: t.ended, Error: errText})
+		t.ended = nil
+		if errors.Is(err, io.EOF) {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		act, err := a.action()
+		if err == errBotQuit {
+			return 0
+		}
+		fmt.Fprintf(scr, "Bot: %s\n", act)
+
+		event, errText = "", ""
+		if err := pressKeys(g, act); err != nil {
+			event, errText = "error", err.Error()
+			if rejected++; rejected == maxBadAnswers {
+				fmt.Fprintln(os.Stderr, t.b.stop(fmt.Errorf("bot made %d moves in a row that weren't allowed, the last: %w", rejected, err)))
+				return 1
+			}
+		} else {
+			rejected = 0
+			event = tableEvent(g)
+		}
+		if autosave != nil {
+			if err := autosave(); err != nil {
+				fmt.Fprintln(scr, "couldn't save:", err)
+			}
+		}
+	}
+}
+
+// tableEvent() describes what the last move did to g, for a botMessage.
+func tableEvent(g *tower.Game) string {
+	v := g.View(false)
+	switch {
+	case v.State == tower.StateBetting:
+		return "round_end"
+	case g.IsGameOver() && g.IsBusted():
+		return "bust"
+	case g.IsGameOver():
+		return "complete"
+	case v.GateUsed && v.GateRow == v.Row:
+		return "gate"
+	}
+	return "deal"
+}
+
+// pressKeys() plays a on g with the keys a player would press: "+" and "-" to change the bet, then "z" to
+// bet it, "z" to hit and "x" to cash out. A round that's over is collected by hitting or cashing out.
+// If a key is turned down, any change to the bet is undone.
+func pressKeys(g *tower.Game, a tower.Action) error {
+	keys := []string{}
+	switch {
+	case g.IsGameOver() && a.Kind != tower.ActionBet:
+		keys = append(keys, "z")
+	case g.State() == tower.StateBetting && a.Kind == tower.ActionBet:
+		if a.Wager != 0 && a.Wager%tower.WagerStep != 0 {
+			return fmt.Errorf("%w: got %d", tower.ErrWagerStep, a.Wager)
+		}
+		if a.Wager != 0 {
+			change, steps := "+", (a.Wager-g.GetWager())/tower.WagerStep
+			if steps < 0 {
+				change, steps = "-", -steps
+			}
+			for i := 0; i < steps; i++ {
+				keys = append(keys, change)
+			}
+		}
+		keys = append(keys, "z")
+	case g.State() == tower.StatePlaying && a.Kind == tower.ActionHit:
+		keys = append(keys, "z")
+	case g.State() == tower.StatePlaying && a.Kind == tower.ActionCashOut:
+		keys = append(keys, "x")
+	default:
+		return fmt.Errorf("%w: %s now", tower.ErrBadAction, a)
+	}
+
+	for i, k := range keys {
+		if err := g.Input(k); err != nil {
+			for _, done := range keys[:i] {
+				if done == "+" {
+					g.Input("-")
+				} else {
+					g.Input("+")
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
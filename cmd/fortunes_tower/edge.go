@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// edgeCmd() plays rounds of a deck by the advice, prints how it chose after every row and the
+// house edge it measured, and returns the exit status.
+func edgeCmd(args []string) int {
+	fs := flag.NewFlagSet("edge", flag.ExitOnError)
+	deckName := fs.String("deck", tower.DiamondDeck.Name, "deck to deal from: "+deckNames())
+	rows := fs.Int("rows", tower.DefaultRows, fmt.Sprintf("tower height, gate row included (%d-%d)", tower.MinRows, tower.MaxRows))
+	rounds := fs.Int("rounds", 1000, "rounds to play, to measure the house edge")
+	seed := fs.Int64("seed", 1, "seed the rounds are shuffled from")
+	maxBet := fs.Int("max-bet", tower.DefaultMaxWager, "largest bet to list the average loss for")
+	asJSON := fs.Bool("json", false, "print the results as JSON")
+	fs.Parse(args)
+
+	deck, err := tower.DeckByName(*deckName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	t, err := tower.MeasureEdge(deck, *rows, *rounds, *seed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(t)
+	} else {
+		err = t.WriteTable(os.Stdout, *maxBet)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// dataDir() returns the directory the game keeps its files in: $XDG_DATA_HOME/fortunes_tower,
+// or ~/.local/share/fortunes_tower if XDG_DATA_HOME isn't set.
+func dataDir() (string, error) {
+	if d := os.Getenv("XDG_DATA_HOME"); d != "" {
+		return filepath.Join(d, "fortunes_tower"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New("can't find a data directory, set XDG_DATA_HOME or --save")
+	}
+	return filepath.Join(home, ".local", "share", "fortunes_tower"), nil
+}
+
+// writeFileAtomic() replaces path with whatever write() writes, so a crash midway
+// leaves the old file in place rather than half a new one.
+func writeFileAtomic(path string, write func(f *os.File) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// sessionFile is what saveGame() writes: the game, and the profile whose money it's played with.
+type sessionFile struct {
+	Profile string          `json:"profile"`
+	Game    json.RawMessage `json:"game"`
+}
+
+// saveGame() writes g, played by the named profile, to path.
+func saveGame(g *tower.Game, profile, path string) error {
+	buf := &bytes.Buffer{}
+	if err := g.Save(buf); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(f *os.File) error {
+		return json.NewEncoder(f).Encode(sessionFile{Profile: profile, Game: buf.Bytes()})
+	})
+}
+
+// loadGame() reads the game saved at path. A game saved by another profile is turned down,
+// so one profile's balance is never played with, or saved into, another's.
+func loadGame(path, profile string, opts ...tower.Option) (tower.Game, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return tower.Game{}, err
+	}
+	var s sessionFile
+	if err := json.Unmarshal(b, &s); err != nil {
+		return tower.Game{}, fmt.Errorf("save file %s is corrupt: %v", path, err)
+	}
+	if s.Profile != profile {
+		return tower.Game{}, fmt.Errorf("the session in %s belongs to profile %q, not %q", path, s.Profile, profile)
+	}
+	return tower.Load(bytes.NewReader(s.Game), opts...)
+}
+
+// defaultProfile is the profile played when --profile isn't given.
+const defaultProfile = "default"
+
+// validProfileName matches names that are safe to use as file names.
+var validProfileName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// profilePath() returns the file the named profile is kept in.
+func profilePath(name string) (string, error) {
+	return profileFile("profiles", name)
+}
+
+// sessionPath() returns the file the named profile's session is saved to unless --save is given.
+func sessionPath(name string) (string, error) {
+	return profileFile("sessions", name)
+}
+
+// profileFile() returns the named profile's file in the data directory's sub directory.
+func profileFile(sub, name string) (string, error) {
+	if !validProfileName.MatchString(name) {
+		return "", fmt.Errorf("profile name %q must only use letters, digits, - and _", name)
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, sub, name+".json"), nil
+}
+
+// loadProfile() reads the named profile, or creates a new one if it doesn't exist yet.
+func loadProfile(name string) (*tower.Profile, error) {
+	path, err := profilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		p := tower.NewProfile(name)
+		return &p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &tower.Profile{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("profile %q is corrupt: %v", name, err)
+	}
+	return p, nil
+}
+
+// saveProfile() writes p to its file.
+func saveProfile(p *tower.Profile) error {
+	path, err := profilePath(p.Name)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(f *os.File) error {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p)
+	})
+}
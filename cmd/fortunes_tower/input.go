@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// keyReader reads the player's next command.
+type keyReader interface {
+	readKey() (string, error)
+}
+
+// lineReader reads one command per line, for piped or scripted input.
+type lineReader struct {
+	r *bufio.Reader
+}
+
+func (l lineReader) readKey() (string, error) {
+	in, err := l.r.ReadString('\n')
+	in = strings.TrimRight(in, "\r\n")
+	if err == io.EOF && in != "" {
+		// the last line has no newline, play it before reporting EOF
+		return in, nil
+	}
+	return in, err
+}
+
+// rawReader reads one command per key press.
+type rawReader struct {
+	r *bufio.Reader
+}
+
+func (k rawReader) readKey() (string, error) {
+	for {
+		b, err := k.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == '\r' || b == '\n' {
+			continue
+		}
+		return string(b), nil
+	}
+}
+
+// newKeyReader() reads single key presses from in if it's a terminal that can be put in raw mode,
+// and lines otherwise. The returned func restores the terminal, it's safe to call more than once.
+func newKeyReader(in *os.File) (keyReader, func()) {
+	r := bufio.NewReader(in)
+	if !tower.IsTerminal(in) {
+		return lineReader{r}, func() {}
+	}
+	restoreTerm, err := makeRaw(int(in.Fd()))
+	if err != nil {
+		return lineReader{r}, func() {}
+	}
+
+	var once sync.Once
+	return rawReader{r}, func() {
+		once.Do(func() { restoreTerm() })
+	}
+}
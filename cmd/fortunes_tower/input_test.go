@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestKeyReaders(t *testing.T) {
+	readAll := func(t *testing.T, k keyReader) []string {
+		t.Helper()
+		keys := []string{}
+		for {
+			key, err := k.readKey()
+			if err == io.EOF {
+				return keys
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	t.Run("line mode reads one command per line", func(t *testing.T) {
+		k := lineReader{bufio.NewReader(strings.NewReader("z\r\nx\n+"))}
+
+		got := readAll(t, k)
+		want := []string{"z", "x", "+"}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("raw mode reads one command per key", func(t *testing.T) {
+		k := rawReader{bufio.NewReader(strings.NewReader("zz\rx-"))}
+
+		got := readAll(t, k)
+		want := []string{"z", "z", "x", "-"}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// maxWatchers is how many connections can watch a table at once.
+const maxWatchers = 100
+
+// watcherBuffer is how many messages can wait for a watcher. One that falls further behind is dropped.
+const watcherBuffer = 64
+
+// The live messages that aren't table events.
+const (
+	liveSnapshot = "snapshot" // the table, when the connection opens
+	liveState    = "state"    // the table, after every move
+	liveError    = "error"    // a move from this connection was turned down
+)
+
+var errSpectator = errors.New("spectators can't play, only the seated player can")
+
+// liveMessage is what a live table sends: a tower.Event as it happens, with the table's "state"
+// after every move's events, a "snapshot" of the table to start with, or an "error".
+type liveMessage struct {
+	tower.Event
+	Table *sessionState `json:"table,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// watcher is a connection watching a table. Messages wait in send until they're written,
+// and send is closed when the watcher is dropped.
+type watcher struct {
+	send chan []byte
+}
+
+// live() returns the handler for a live table's WebSocket, for the seated player if seated is
+// true, or a spectator. Everyone connected gets a snapshot of the table, then every move as it's
+// played, whoever plays it. The seated player can play over the connection too, with the same
+// JSON a bot answers with, {"action":"hit"}; if the move is turned down, only they are told.
+// Connections are pinged every wsPingInterval, and one that sends nothing back for wsReadTimeout is dropped.
+func (st *sessionStore) live(seated bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var s *session
+		if seated {
+			s = st.get(r.PathValue("id"))
+		} else {
+			s = st.getTable(r.PathValue("table"))
+		}
+		if s == nil {
+			writeError(w, http.StatusNotFound, errors.New("no such table"))
+			return
+		}
+		// the seat is taken, and the snapshot queued ahead of any move, before the lock is let go,
+		// so the table can't fill up behind the check
+		wt := &watcher{send: make(chan []byte, watcherBuffer)}
+		s.mu.Lock()
+		switch {
+		case s.ended:
+			s.mu.Unlock()
+			writeError(w, http.StatusNotFound, errors.New("no such table"))
+			return
+		case len(s.watchers) >= maxWatchers:
+			s.mu.Unlock()
+			writeError(w, http.StatusServiceUnavailable, errors.New("the table is full, try again later"))
+			return
+		}
+		s.watchers[wt] = true
+		state := s.publicState()
+		s.sendTo(wt, liveMessage{Event: tower.Event{Kind: liveSnapshot}, Table: &state})
+		s.mu.Unlock()
+
+		c, err := upgradeWebSocket(w, r)
+		if err != nil {
+			s.leave(wt)
+			return
+		}
+
+		go func() {
+			defer s.leave(wt)
+			for {
+				msg, err := c.read()
+				if err != nil {
+					return
+				}
+				if seated {
+					st.get(s.id) // the player is still there
+				}
+				s.mu.Lock()
+				if err := s.liveMove(seated, msg); err != nil {
+					s.sendTo(wt, liveMessage{Event: tower.Event{Kind: liveError}, Error: err.Error()})
+				}
+				s.mu.Unlock()
+			}
+		}()
+		ping := time.NewTicker(wsPingInterval)
+		defer ping.Stop()
+	write:
+		for {
+			select {
+			case msg, ok := <-wt.send:
+				if !ok || c.writeText(msg) != nil {
+					break write
+				}
+			case <-ping.C:
+				if c.write(wsPing, nil) != nil {
+					break write
+				}
+			}
+		}
+		s.leave(wt)
+		c.close()
+	}
+}
+
+// liveMove() plays a move sent over a live table's connection.
+func (s *session) liveMove(seated bool, msg []byte) error {
+	if !seated {
+		return errSpectator
+	}
+	var a tower.Action
+	if err := json.Unmarshal(msg, &a); err != nil {
+		return fmt.Errorf("can't read %q, want an action of bet, hit or cash_out: %v", msg, err)
+	}
+	_, err := s.move(a)
+	return err
+}
+
+// broadcast() tells everyone watching what the last move did, then how the table stands.
+func (s *session) broadcast() {
+	msgs := []liveMessage{}
+	for _, e := range s.events {
+		msgs = append(msgs, liveMessage{Event: e})
+	}
+	s.events = nil
+	state := s.publicState()
+	msgs = append(msgs, liveMessage{Event: tower.Event{Kind: liveState}, Table: &state})
+
+	for wt := range s.watchers {
+		for _, m := range msgs {
+			s.sendTo(wt, m)
+		}
+	}
+}
+
+// publicState() returns the session's state without its ID, for spectators.
+func (s *session) publicState() sessionState {
+	state := s.state()
+	state.ID = ""
+	return state
+}
+
+// sendTo() queues m for wt, dropping it if it has fallen too far behind.
+func (s *session) sendTo(wt *watcher, m liveMessage) {
+	if !s.watchers[wt] {
+		return
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	select {
+	case wt.send <- b:
+	default:
+		s.drop(wt)
+	}
+}
+
+// drop() stops sending to wt, which closes its connection once what's queued is written.
+func (s *session) drop(wt *watcher) {
+	if s.watchers[wt] {
+		delete(s.watchers, wt)
+		close(wt.send)
+	}
+}
+
+// dropAll() drops everyone watching.
+func (s *session) dropAll() {
+	for wt := range s.watchers {
+		s.drop(wt)
+	}
+}
+
+// leave() drops wt, locking the session.
+func (s *session) leave(wt *watcher) {
+	s.mu.Lock()
+	s.drop(wt)
+	s.mu.Unlock()
+}
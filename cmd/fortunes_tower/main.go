@@ -0,0 +1,281 @@
+// Command fortunes_tower plays Fortune's Tower in the terminal.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+func main() {
+	args := os.Args[1:]
+	command := "play"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "play":
+		os.Exit(playCmd(args))
+	case "stats":
+		os.Exit(statsCmd(args))
+	case "replay":
+		os.Exit(replayCmd(args))
+	case "edge":
+		os.Exit(edgeCmd(args))
+	case "simulate":
+		os.Exit(simulateCmd(args))
+	case "autoplay":
+		os.Exit(autoplayCmd(args))
+	case "tournament":
+		os.Exit(tournamentCmd(args))
+	case "table":
+		os.Exit(tableCmd(args))
+	case "serve":
+		os.Exit(serveCmd(args))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, want play, stats, replay, edge, simulate, autoplay, tournament, table or serve\n", command)
+		os.Exit(2)
+	}
+}
+
+// playCmd() plays the game in the terminal and returns the exit status.
+func playCmd(args []string) int {
+	fs := flag.NewFlagSet("play", flag.ExitOnError)
+	seed := fs.Int64("seed", 0, "seed for every shuffle in the session (default random)")
+	maxBet := fs.Int("max-bet", tower.DefaultMaxWager, "table maximum bet, a multiple of 15")
+	deckName := fs.String("deck", tower.DiamondDeck.Name, "deck to deal from: "+deckNames())
+	rows := fs.Int("rows", tower.DefaultRows, fmt.Sprintf("tower height, gate row included (%d-%d)", tower.MinRows, tower.MaxRows))
+	colorFlag := fs.String("color", "auto", "color cards: auto, always or never")
+	savePath := fs.String("save", "", "file the session is saved to after every action (default $XDG_DATA_HOME/fortunes_tower/sessions/<profile>.json)")
+	resume := fs.Bool("resume", false, "continue the session in the save file, ignoring --seed, --deck, --rows and --max-bet")
+	profileName := fs.String("profile", defaultProfile, "profile that keeps your balance and statistics")
+	logPath := fs.String("log", "", "append a JSON Lines record of every round to this file")
+	showOdds := fs.Bool("odds", false, "show the odds of the next deal (\"o\" toggles them in game)")
+	botCmd := fs.String("bot-cmd", "", "let this program play instead of the keyboard, see the README for how it's spoken to")
+	botTimeout := fs.Duration("bot-timeout", defaultBotTimeout, "how long the bot has to answer")
+	botTranscript := fs.String("bot-transcript", "", "write everything said to and by the bot to this file")
+	botDelay := fs.Duration("bot-delay", time.Second/5, "pause between the bot's moves")
+	botCounts := fs.Bool("bot-counts", false, "let the bot see how many of each card are left")
+	fs.Parse(args)
+
+	if *savePath == "" {
+		path, err := sessionPath(*profileName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		*savePath = path
+	}
+
+	color, err := colorMode(*colorFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	deck, err := tower.DeckByName(*deckName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	profile, err := loadProfile(*profileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	sum := &summary{}
+	hooks := []tower.Option{tower.WithColor(color), tower.WithRoundEnd(sum.add), tower.WithRoundEnd(profile.Add)}
+	var roundLog *tower.LogWriter
+	if *logPath != "" {
+		f, err := os.OpenFile(*logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer f.Close()
+		roundLog = tower.NewLogWriter(f)
+		hooks = append(hooks, tower.WithRoundLog(roundLog.Write))
+	}
+	var table *botTable
+	if *botCmd != "" {
+		var transcript io.Writer
+		if *botTranscript != "" {
+			f, err := os.Create(*botTranscript)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			defer f.Close()
+			transcript = f
+		}
+		b, err := startBot(*botCmd, *botTimeout, transcript)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer b.close()
+		table = &botTable{b: b, counts: *botCounts, delay: *botDelay}
+		hooks = append(hooks, tower.WithRoundEnd(table.roundEnd))
+	}
+	var g tower.Game
+	if *resume {
+		g, err = loadGame(*savePath, profile.Name, hooks...)
+	} else {
+		opts := []tower.Option{tower.WithMaxWager(*maxBet), tower.WithDeck(deck), tower.WithRows(*rows), tower.WithBalance(profile.Balance)}
+		if flagSet(fs, "seed") {
+			opts = append(opts, tower.WithSeed(*seed))
+		}
+		g, err = tower.NewGame(append(opts, hooks...)...)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	sum.startBalance = g.Balance()
+
+	scr := newScreen(os.Stdout, fmt.Sprintf("Seed: %d\n", g.Seed()))
+	g.SetOutput(scr)
+	if *resume {
+		g.PrintTower()
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	autosave := func() error {
+		profile.SetBalance(g.Balance())
+		if err := saveProfile(profile); err != nil {
+			return err
+		}
+		if roundLog != nil && roundLog.Err() != nil {
+			return fmt.Errorf("round log: %w", roundLog.Err())
+		}
+		return saveGame(&g, profile.Name, *savePath)
+	}
+	var code int
+	if table != nil {
+		code = table.play(&g, scr, sigs, autosave)
+	} else {
+		keys, restore := newKeyReader(os.Stdin)
+		defer restore() // also runs if the game panics
+		code = play(&g, scr, keys, sigs, *showOdds, autosave)
+		restore()
+	}
+	fmt.Print(sum.report(g.Balance()))
+	return code
+}
+
+// keyEvent is a key read by play()'s input goroutine.
+type keyEvent struct {
+	key string
+	err error
+}
+
+// play() runs the game until the player quits, input ends or a signal arrives,
+// and returns the exit status: 0 for q, quit or end of input, 1 if input fails,
+// and 128 + the signal number for a signal. showOdds shows the odds of the next deal, "o" toggles them,
+// and "?" explains whether to hit or cash out.
+// If autosave isn't nil it's called after every action.
+func play(g *tower.Game, scr *screen, keys keyReader, sigs <-chan os.Signal, showOdds bool, autosave func() error) int {
+	input := make(chan keyEvent)
+	go func() {
+		for {
+			key, err := keys.readKey()
+			input <- keyEvent{key, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		g.PrintText()
+		if showOdds {
+			g.PrintOdds()
+		}
+		scr.flush()
+
+		select {
+		case sig := <-sigs:
+			fmt.Fprintln(scr.out)
+			if s, ok := sig.(syscall.Signal); ok {
+				return 128 + int(s)
+			}
+			return 1
+		case k := <-input:
+			if k.err == io.EOF {
+				return 0
+			}
+			if k.err != nil {
+				fmt.Fprintln(os.Stderr, k.err)
+				return 1
+			}
+			if k.key == "q" || k.key == "quit" {
+				return 0
+			}
+			switch k.key {
+			case "o":
+				showOdds = !showOdds
+				g.PrintTower()
+				continue
+			case "?":
+				g.PrintTower()
+				g.PrintAdvice()
+				continue
+			}
+			if err := g.Input(k.key); err != nil {
+				fmt.Fprintln(scr, err)
+			}
+			if autosave != nil {
+				if err := autosave(); err != nil {
+					fmt.Fprintln(scr, "couldn't save:", err)
+				}
+			}
+			g.PrintTower()
+			time.Sleep(time.Second / 5)
+		}
+	}
+}
+
+// colorMode() parses the --color flag. The game prints into the screen's frame buffer,
+// so auto is decided here against the real stdout.
+func colorMode(flag string) (tower.ColorMode, error) {
+	color, err := tower.ParseColorMode(flag)
+	if err != nil || color != tower.ColorAuto {
+		return color, err
+	}
+	if tower.ColorEnabled(os.Stdout) {
+		return tower.ColorAlways, nil
+	}
+	return tower.ColorNever, nil
+}
+
+// deckNames() lists the built-in decks for the --deck usage text.
+func deckNames() string {
+	names := []string{}
+	for _, d := range tower.Decks {
+		names = append(names, d.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// flagSet() reports whether the named flag was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
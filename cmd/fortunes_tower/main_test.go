@@ -0,0 +1,1162 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+func TestPlay(t *testing.T) {
+	start := func(t *testing.T, input string) (*tower.Game, *screen, keyReader) {
+		t.Helper()
+		g, err := tower.NewGame(tower.WithSeed(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		scr := newScreen(&bytes.Buffer{}, "")
+		g.SetOutput(scr)
+		return &g, scr, lineReader{bufio.NewReader(strings.NewReader(input))}
+	}
+
+	for _, in := range []string{"q\n", "quit\n", ""} {
+		t.Run(fmt.Sprintf("%q exits cleanly", in), func(t *testing.T) {
+			g, scr, keys := start(t, in)
+
+			if code := play(g, scr, keys, nil, false, nil); code != 0 {
+				t.Fatalf("want exit status 0, got %d", code)
+			}
+		})
+	}
+
+	t.Run("input after quit is ignored", func(t *testing.T) {
+		g, scr, keys := start(t, "q\nz\n")
+
+		play(g, scr, keys, nil, false, nil)
+
+		if g.State() != tower.StateBetting {
+			t.Fatalf("game should not have been played after quitting")
+		}
+	})
+
+	t.Run("the game is saved after every action", func(t *testing.T) {
+		g, scr, keys := start(t, "z\nz\nx\n")
+		saves := 0
+
+		play(g, scr, keys, nil, false, func() error { saves++; return nil })
+
+		if saves != 3 {
+			t.Fatalf("want 3 saves, got %d", saves)
+		}
+	})
+
+	t.Run("o toggles the odds", func(t *testing.T) {
+		g, scr, keys := start(t, "o\nz\no\n")
+		out := scr.out.(*bytes.Buffer)
+
+		play(g, scr, keys, nil, false, nil)
+
+		frames := strings.Split(out.String(), "Money: ")
+		if len(frames) != 5 {
+			t.Fatalf("want 4 frames, got %d:\n%s", len(frames)-1, out)
+		}
+		for i, want := range []bool{false, true, true, false} {
+			if got := strings.Contains(frames[i+1], "odds:"); got != want {
+				t.Errorf("frame %d: want odds shown %t, got %t", i, want, got)
+			}
+		}
+		if g.State() != tower.StatePlaying {
+			t.Fatalf("o shouldn't be played as a move")
+		}
+	})
+
+	t.Run("? gives advice without playing", func(t *testing.T) {
+		g, scr, keys := start(t, "?\nz\n?\n")
+		out := scr.out.(*bytes.Buffer)
+
+		play(g, scr, keys, nil, false, nil)
+
+		if n := strings.Count(out.String(), "Advice: ") + strings.Count(out.String(), "No advice: "); n != 1 {
+			t.Fatalf("want advice once, mid round, got %d:\n%s", n, out)
+		}
+		if g.State() != tower.StatePlaying || g.CurRow() != 2 {
+			t.Fatalf("? shouldn't be played as a move")
+		}
+	})
+
+	t.Run("signals exit with 128 + the signal number", func(t *testing.T) {
+		g, scr, _ := start(t, "")
+		sigs := make(chan os.Signal, 1)
+		sigs <- syscall.SIGTERM
+
+		if code := play(g, scr, blockingReader{}, sigs, false, nil); code != 128+int(syscall.SIGTERM) {
+			t.Fatalf("want exit status %d, got %d", 128+int(syscall.SIGTERM), code)
+		}
+	})
+}
+
+func TestSaveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir", "session.json")
+	g, err := tower.NewGame(tower.WithSeed(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Input("z")
+
+	if err := saveGame(&g, "alice", path); err != nil {
+		t.Fatalf("saveGame() returned error: %v", err)
+	}
+	loaded, err := loadGame(path, "alice")
+	if err != nil {
+		t.Fatalf("loadGame() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Tower(), g.Tower()) || loaded.Balance() != g.Balance() {
+		t.Fatalf("loaded game doesn't match the saved one")
+	}
+
+	if _, err := loadGame(path, "bob"); err == nil || !strings.Contains(err.Error(), `belongs to profile "alice"`) {
+		t.Fatalf("another profile's session should be turned down, got %v", err)
+	}
+	if _, err := loadGame(filepath.Join(t.TempDir(), "missing.json"), "alice"); err == nil {
+		t.Fatalf("loading a missing file should fail")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	t.Run("a new profile starts with the starting balance", func(t *testing.T) {
+		p, err := loadProfile("fresh")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Balance != tower.StartingBalance || p.Rounds != 0 {
+			t.Fatalf("want a fresh profile, got %+v", p)
+		}
+	})
+
+	t.Run("profiles are kept between loads", func(t *testing.T) {
+		p, _ := loadProfile("kept")
+		p.Add(tower.Result{Wager: 15, Payout: 30})
+		p.SetBalance(315)
+		if err := saveProfile(p); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := loadProfile("kept")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loaded, p) {
+			t.Fatalf("want %+v, got %+v", p, loaded)
+		}
+	})
+
+	t.Run("stats -reset wipes the profile", func(t *testing.T) {
+		p, _ := loadProfile("wiped")
+		p.Add(tower.Result{Wager: 15})
+		saveProfile(p)
+
+		if code := statsCmd([]string{"-profile", "wiped", "-reset"}); code != 0 {
+			t.Fatalf("want exit status 0, got %d", code)
+		}
+		loaded, _ := loadProfile("wiped")
+		if loaded.Rounds != 0 {
+			t.Fatalf("profile should have been reset, got %+v", loaded)
+		}
+	})
+
+	t.Run("each profile saves its session to its own file", func(t *testing.T) {
+		alice, err := sessionPath("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		bob, _ := sessionPath("bob")
+		if alice == bob {
+			t.Fatalf("alice and bob share the session file %s", alice)
+		}
+		if _, err := sessionPath("../escape"); err == nil {
+			t.Fatalf("want an error for an unsafe name")
+		}
+	})
+
+	t.Run("names that aren't safe file names are rejected", func(t *testing.T) {
+		if _, err := loadProfile("../escape"); err == nil {
+			t.Fatalf("want an error for an unsafe name")
+		}
+	})
+}
+
+func TestSummary(t *testing.T) {
+	s := &summary{startBalance: 300}
+	s.add(tower.Result{Wager: 15, Payout: 45})
+	s.add(tower.Result{Wager: 15, Bust: true})
+	s.add(tower.Result{Wager: 15, Payout: 12})
+
+	want := "Rounds played: 3\nNet: won 12\nBiggest payout: 45\n"
+	if got := s.report(312); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	// writeLog() plays a few rounds into a round log and returns its path.
+	writeLog := func(t *testing.T) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "rounds.jsonl")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		g, err := tower.NewGame(tower.WithSeed(4), tower.WithRoundLog(tower.NewLogWriter(f).Write))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, in := range []string{"z", "z", "x", "z", "x", "z", "z", "z", "x"} {
+			g.Input(in)
+		}
+		return path
+	}
+
+	t.Run("logged rounds match the engine", func(t *testing.T) {
+		replays, err := loadReplays(writeLog(t), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		if code := checkReplays(out, replays); code != 0 {
+			t.Fatalf("want exit status 0, got %d: %s", code, out)
+		}
+		if want := fmt.Sprintf("%d of %d rounds match", len(replays), len(replays)); !strings.Contains(out.String(), want) {
+			t.Fatalf("want %q, got %q", want, out)
+		}
+	})
+
+	t.Run("a changed outcome fails the check", func(t *testing.T) {
+		path := writeLog(t)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = bytes.Replace(data, []byte(`"wager":15`), []byte(`"wager":30`), 1)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		replays, err := loadReplays(path, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := checkReplays(&bytes.Buffer{}, replays); code != 1 {
+			t.Fatalf("want exit status 1, got %d", code)
+		}
+	})
+
+	t.Run("rounds outside the log are rejected", func(t *testing.T) {
+		if _, err := loadReplays(writeLog(t), 99); err == nil {
+			t.Fatalf("want an error")
+		}
+	})
+
+	t.Run("stepping moves forward and back through the rounds", func(t *testing.T) {
+		replays, err := loadReplays(writeLog(t), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		keys := lineReader{bufio.NewReader(strings.NewReader("z\nz\nb\nq\n"))}
+
+		if code := stepThrough(replays, newScreen(out, ""), keys, nil); code != 0 {
+			t.Fatalf("want exit status 0, got %d", code)
+		}
+		frames := strings.Split(out.String(), "Round ")[1:]
+		want := []string{"1 of 3", "1 of 3", "2 of 3", "1 of 3"}
+		if len(frames) != len(want) {
+			t.Fatalf("want %d frames, got %d:\n%s", len(want), len(frames), out)
+		}
+		for i, f := range frames {
+			if !strings.HasPrefix(f, want[i]) {
+				t.Errorf("frame %d should show round %s, got %q", i, want[i], f)
+			}
+		}
+		if !strings.Contains(out.String(), "Engine matches the record.") {
+			t.Fatalf("replay should say the engine matches the record")
+		}
+	})
+}
+
+func TestAutoplay(t *testing.T) {
+	start := func(t *testing.T, opts ...tower.Option) (*tower.Game, *screen, *bytes.Buffer) {
+		t.Helper()
+		out := &bytes.Buffer{}
+		scr := newScreen(out, "")
+		opts = append([]tower.Option{tower.WithSeed(2), tower.WithRoundEnd(func(r tower.Result) { printResult(scr, r) })}, opts...)
+		g, err := tower.NewGame(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.SetOutput(scr)
+		return &g, scr, out
+	}
+
+	t.Run("plays the rounds asked for, showing every move", func(t *testing.T) {
+		g, scr, out := start(t)
+
+		if code := autoplay(g, tower.HitTo(2), nil, false, scr, nil, 3, 0); code != 0 {
+			t.Fatalf("want exit status 0, got %d", code)
+		}
+		if n := strings.Count(out.String(), "Strategy: bet 15\n"); n != 3 {
+			t.Fatalf("want 3 bets, got %d:\n%s", n, out)
+		}
+		if n := strings.Count(out.String(), "Paid ") + strings.Count(out.String(), "Bust on row"); n != 3 {
+			t.Fatalf("want 3 results, got %d:\n%s", n, out)
+		}
+		if g.State() != tower.StateBetting {
+			t.Fatalf("should stop between rounds")
+		}
+	})
+
+	t.Run("stops when the money runs out", func(t *testing.T) {
+		g, scr, out := start(t, tower.WithBalance(10))
+
+		if code := autoplay(g, tower.HitTo(2), nil, false, scr, nil, 0, 0); code != 0 {
+			t.Fatalf("want exit status 0, got %d", code)
+		}
+		if !strings.Contains(out.String(), "Out of money.") {
+			t.Fatalf("want out of money, got:\n%s", out)
+		}
+	})
+
+	t.Run("a move that isn't allowed exits with 1", func(t *testing.T) {
+		g, scr, out := start(t)
+		hit := tower.StrategyFunc(func(tower.View, *rand.Rand) (tower.Action, error) { return tower.Action{Kind: tower.ActionHit}, nil })
+
+		if code := autoplay(g, hit, nil, false, scr, nil, 1, 0); code != 1 {
+			t.Fatalf("want exit status 1, got %d", code)
+		}
+		if !strings.Contains(out.String(), "isn't allowed") {
+			t.Fatalf("want the error shown, got:\n%s", out)
+		}
+	})
+
+	t.Run("signals exit with 128 + the signal number", func(t *testing.T) {
+		g, scr, _ := start(t)
+		sigs := make(chan os.Signal, 1)
+		sigs <- syscall.SIGINT
+
+		if code := autoplay(g, tower.HitTo(2), nil, false, scr, sigs, 0, time.Hour); code != 128+int(syscall.SIGINT) {
+			t.Fatalf("want exit status %d, got %d", 128+int(syscall.SIGINT), code)
+		}
+	})
+}
+
+func TestTournament(t *testing.T) {
+	// The test binary plays the bot, see TestBotProcess.
+	botCmd := func(t *testing.T, play string) string {
+		t.Setenv("FORTUNES_TOWER_TEST_BOT", play)
+		return "bot=" + os.Args[0] + " -test.run=^TestBotProcess$"
+	}
+	cfg := tower.TournamentConfig{Deck: tower.DiamondDeck, Rows: tower.DefaultRows, Wager: 15, Seed: 2, Sessions: 3, SessionRounds: 20, Bankroll: 300}
+
+	t.Run("a bot plays like the built-in it copies", func(t *testing.T) {
+		entrants, closeBots, err := newEntrants([]string{"hit-to:3"}, []string{botCmd(t, "hit-to:3")}, time.Second)
+		defer closeBots()
+		if err != nil {
+			t.Fatal(err)
+		}
+		standings, err := tower.Tournament(cfg, entrants)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, b := standings[0], standings[1]
+		b.Name = a.Name
+		if a != b {
+			t.Fatalf("want the same standings, got %+v and %+v", standings[0], standings[1])
+		}
+	})
+
+	t.Run("a bot answering nonsense fails", func(t *testing.T) {
+		entrants, closeBots, err := newEntrants(nil, []string{botCmd(t, "nonsense")}, time.Second)
+		defer closeBots()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tower.Tournament(cfg, entrants); err == nil || !strings.Contains(err.Error(), "bad answers") {
+			t.Fatalf("want an error about the answer, got %v", err)
+		}
+	})
+
+	t.Run("bad entrants are rejected", func(t *testing.T) {
+		for _, tc := range []struct{ names, bots []string }{
+			{[]string{"always"}, nil},
+			{nil, []string{"no command"}},
+			{nil, []string{"=cmd"}},
+			{nil, []string{"missing=./no-such-bot"}},
+		} {
+			_, closeBots, err := newEntrants(tc.names, tc.bots, time.Second)
+			closeBots()
+			if err == nil {
+				t.Errorf("%v %v: want an error", tc.names, tc.bots)
+			}
+		}
+	})
+}
+
+func TestBot(t *testing.T) {
+	start := func(t *testing.T, play string, transcript io.Writer) *bot {
+		t.Helper()
+		t.Setenv("FORTUNES_TOWER_TEST_BOT", play)
+		b, err := startBot(os.Args[0]+" -test.run=^TestBotProcess$", time.Second, transcript)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { b.close() })
+		return b
+	}
+	newTable := func(t *testing.T, b *bot, opts ...tower.Option) (*tower.Game, *botTable, *screen) {
+		t.Helper()
+		table := &botTable{b: b}
+		g, err := tower.NewGame(append([]tower.Option{tower.WithSeed(3), tower.WithRoundEnd(table.roundEnd)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scr := newScreen(&bytes.Buffer{}, "")
+		g.SetOutput(scr)
+		return &g, table, scr
+	}
+
+	t.Run("a bot plays through the keys until it quits", func(t *testing.T) {
+		t.Setenv("FORTUNES_TOWER_TEST_BOT_ROUNDS", "3")
+		transcript := &bytes.Buffer{}
+		g, table, scr := newTable(t, start(t, "hit-to:2", transcript))
+		saves := 0
+
+		if code := table.play(g, scr, nil, func() error { saves++; return nil }); code != 0 {
+			t.Fatalf("want exit status 0, got %d", code)
+		}
+		if n := strings.Count(transcript.String(), `"event":"round_end"`); n != 3 {
+			t.Fatalf("want 3 rounds, got %d:\n%s", n, transcript)
+		}
+		if !strings.HasPrefix(transcript.String(), `> {"event":"start","state":0,`) || !strings.Contains(transcript.String(), "\n< {\"action\":\"quit\"}\n") {
+			t.Fatalf("transcript should run from start to quit, got:\n%s", transcript)
+		}
+		if !strings.Contains(transcript.String(), `"result":{"wager":15,`) {
+			t.Fatalf("the bot should be told how rounds went, got:\n%s", transcript)
+		}
+		if saves == 0 || g.State() != tower.StateBetting {
+			t.Fatalf("want saves after moves, and the game left between rounds")
+		}
+	})
+
+	t.Run("bad answers are turned down, then the bot is stopped", func(t *testing.T) {
+		transcript := &bytes.Buffer{}
+		b := start(t, "nonsense", transcript)
+
+		_, err := b.ask(botMessage{Event: "start"})
+		if err == nil || !strings.Contains(err.Error(), "3 bad answers") {
+			t.Fatalf("want an error after 3 bad answers, got %v", err)
+		}
+		if n := strings.Count(transcript.String(), `"event":"error"`); n != 2 {
+			t.Fatalf("want the bot told twice what was wrong, got:\n%s", transcript)
+		}
+		if _, again := b.ask(botMessage{Event: "start"}); again != err {
+			t.Fatalf("a stopped bot should fail every message, got %v", again)
+		}
+	})
+
+	t.Run("a bot that doesn't answer times out", func(t *testing.T) {
+		b := start(t, "silent", nil)
+		b.timeout = 50 * time.Millisecond
+
+		if _, err := b.ask(botMessage{Event: "start"}); err == nil || !strings.Contains(err.Error(), "didn't answer within") {
+			t.Fatalf("want a timeout, got %v", err)
+		}
+	})
+
+	t.Run("moves the game turns down are told to the bot", func(t *testing.T) {
+		t.Setenv("FORTUNES_TOWER_TEST_BOT_BET", "20")
+		transcript := &bytes.Buffer{}
+		g, table, scr := newTable(t, start(t, "hit-to:2", transcript))
+
+		if code := table.play(g, scr, nil, nil); code != 1 {
+			t.Fatalf("want exit status 1, got %d", code)
+		}
+		if n := strings.Count(transcript.String(), `"error":"bet must be a multiple of 15`); n != 2 {
+			t.Fatalf("want the bot told twice, then stopped, got:\n%s", transcript)
+		}
+	})
+
+	t.Run("answers are pressed as keys", func(t *testing.T) {
+		g, _, _ := newTable(t, nil, tower.WithBalance(100))
+
+		if err := pressKeys(g, tower.Action{Kind: tower.ActionHit}); !errors.Is(err, tower.ErrBadAction) {
+			t.Fatalf("want hitting before betting turned down, got %v", err)
+		}
+		if err := pressKeys(g, tower.Action{Kind: tower.ActionBet, Wager: 120}); !errors.Is(err, tower.ErrInsufficientBalance) || g.GetWager() != 15 {
+			t.Fatalf("want a bet over the balance turned down and undone, got %v and a bet of %d", err, g.GetWager())
+		}
+		if err := pressKeys(g, tower.Action{Kind: tower.ActionBet, Wager: 45}); err != nil || g.GetWager() != 45 || g.State() != tower.StatePlaying {
+			t.Fatalf("want a round started with a bet of 45, got %v and a bet of %d", err, g.GetWager())
+		}
+		if tableEvent(g) != "deal" {
+			t.Fatalf("want a deal event, got %s", tableEvent(g))
+		}
+		if err := pressKeys(g, tower.Action{Kind: tower.ActionBet, Wager: 15}); !errors.Is(err, tower.ErrBadAction) {
+			t.Fatalf("want betting mid-round turned down, got %v", err)
+		}
+		if err := pressKeys(g, tower.Action{Kind: tower.ActionCashOut}); err != nil || g.State() != tower.StateBetting {
+			t.Fatalf("want a cash out, got %v", err)
+		}
+		if tableEvent(g) != "round_end" {
+			t.Fatalf("want a round end event, got %s", tableEvent(g))
+		}
+	})
+
+	t.Run("a bet can be lowered after a loss leaves it over the balance", func(t *testing.T) {
+		var g *tower.Game
+		for seed := int64(1); g == nil; seed++ {
+			g, _, _ = newTable(t, nil, tower.WithSeed(seed), tower.WithBalance(200))
+			if err := pressKeys(g, tower.Action{Kind: tower.ActionBet, Wager: 150}); err != nil {
+				t.Fatal(err)
+			}
+			for !g.IsGameOver() {
+				pressKeys(g, tower.Action{Kind: tower.ActionHit})
+			}
+			pressKeys(g, tower.Action{Kind: tower.ActionHit})
+			if g.Balance() != 50 {
+				g = nil
+			}
+		}
+
+		if err := pressKeys(g, tower.Action{Kind: tower.ActionBet, Wager: 45}); err != nil || g.GetWager() != 45 || g.State() != tower.StatePlaying {
+			t.Fatalf("want a round started with a bet of 45, got %v and a bet of %d", err, g.GetWager())
+		}
+	})
+}
+
+func TestServe(t *testing.T) {
+	newServer := func(t *testing.T, maxSessions int) *httptest.Server {
+		t.Helper()
+		st := newSessionStore(tower.StartingBalance, tower.DefaultMaxWager, maxSessions)
+		st.allowSeed = true
+		srv := httptest.NewServer(st.handler())
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	// do() sends body to path, checks the status, and reads the answer into v if it isn't nil.
+	do := func(t *testing.T, srv *httptest.Server, method, path, body string, status int, v any) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != status {
+			t.Fatalf("%s %s: want status %d, got %d: %s", method, path, status, resp.StatusCode, b)
+		}
+		if v != nil {
+			if err := json.Unmarshal(b, v); err != nil {
+				t.Fatalf("%s %s: %v: %s", method, path, err, b)
+			}
+		}
+	}
+	create := func(t *testing.T, srv *httptest.Server, body string) sessionState {
+		t.Helper()
+		var st sessionState
+		do(t, srv, "POST", "/sessions", body, http.StatusCreated, &st)
+		return st
+	}
+
+	t.Run("a session plays the same as a game on the same seed", func(t *testing.T) {
+		srv := newServer(t, 10)
+		st := create(t, srv, `{"seed": 5, "rows": 6}`)
+		g, err := tower.NewGame(tower.WithSeed(5), tower.WithRows(6))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.SetOutput(io.Discard)
+		if st.Event != "start" || !reflect.DeepEqual(st.View, g.View(false)) {
+			t.Fatalf("want a new session to look like a new game, got %+v", st)
+		}
+
+		moves := []struct {
+			path, body string
+			a          tower.Action
+		}{
+			{"bet", `{"wager": 30}`, tower.Action{Kind: tower.ActionBet, Wager: 30}},
+			{"hit", "", tower.Action{Kind: tower.ActionHit}},
+			{"cash-out", "", tower.Action{Kind: tower.ActionCashOut}},
+		}
+		for round := 0; round < 3; round++ {
+			for _, m := range moves {
+				if m.path == "cash-out" && g.IsGameOver() {
+					m.path = "hit" // a bust is collected by either
+				}
+				do(t, srv, "POST", "/sessions/"+st.ID+"/"+m.path, m.body, http.StatusOK, &st)
+				if err := pressKeys(&g, m.a); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(st.View, g.View(false)) || st.Event != tableEvent(&g) {
+					t.Fatalf("round %d, %s: the session and the game differ:\n%+v\n%+v", round+1, m.path, st, g.View(false))
+				}
+			}
+			if st.Result == nil || st.Result.Wager != 30 {
+				t.Fatalf("want the round's result, got %+v", st.Result)
+			}
+		}
+
+		var history []tower.Record
+		do(t, srv, "GET", "/sessions/"+st.ID+"/history", "", http.StatusOK, &history)
+		if len(history) != 3 || history[0].Seed != 5 || history[0].Round != 1 || history[2].Result != *st.Result {
+			t.Fatalf("want 3 rounds of history, the last ending %+v, got %+v", st.Result, history)
+		}
+		var again sessionState
+		do(t, srv, "GET", "/sessions/"+st.ID, "", http.StatusOK, &again)
+		if !reflect.DeepEqual(again, st) {
+			t.Fatalf("want the state the last move answered with, got %+v", again)
+		}
+	})
+
+	t.Run("bad requests are turned down", func(t *testing.T) {
+		srv := newServer(t, 2)
+		id := create(t, srv, "").ID
+		var e apiError
+		do(t, srv, "POST", "/sessions/"+id+"/hit", "", http.StatusConflict, &e)
+		if !strings.Contains(e.Error, "isn't allowed") {
+			t.Fatalf("want hitting before a bet turned down, got %q", e.Error)
+		}
+		do(t, srv, "POST", "/sessions/"+id+"/bet", `{"wager": 20}`, http.StatusBadRequest, &e)
+		do(t, srv, "POST", "/sessions/"+id+"/bet", `{"wager": 3000}`, http.StatusBadRequest, &e)
+		do(t, srv, "POST", "/sessions/"+id+"/bet", `{"wagr": 30}`, http.StatusBadRequest, &e)
+		do(t, srv, "POST", "/sessions/"+id+"/bet", "", http.StatusOK, nil)
+		do(t, srv, "POST", "/sessions/"+id+"/bet", "", http.StatusConflict, &e)
+		do(t, srv, "GET", "/sessions/nope", "", http.StatusNotFound, &e)
+		do(t, srv, "PUT", "/sessions/"+id, "", http.StatusMethodNotAllowed, nil)
+		do(t, srv, "POST", "/sessions", `{"deck": "paper"}`, http.StatusBadRequest, &e)
+		do(t, srv, "POST", "/sessions", `{"rows": 30}`, http.StatusBadRequest, &e)
+
+		create(t, srv, "")
+		do(t, srv, "POST", "/sessions", "", http.StatusServiceUnavailable, &e)
+		do(t, srv, "DELETE", "/sessions/"+id, "", http.StatusNoContent, nil)
+		do(t, srv, "DELETE", "/sessions/"+id, "", http.StatusNotFound, &e)
+		create(t, srv, "")
+	})
+
+	t.Run("sessions can't choose their seed unless the server allows it", func(t *testing.T) {
+		srv := httptest.NewServer(newSessionStore(tower.StartingBalance, tower.DefaultMaxWager, 10).handler())
+		defer srv.Close()
+		var e apiError
+		do(t, srv, "POST", "/sessions", `{"seed": 5}`, http.StatusBadRequest, &e)
+		if e.Error != errSeedNotAllowed.Error() {
+			t.Fatalf("want the seed turned down, got %q", e.Error)
+		}
+		create(t, srv, `{"rows": 6}`)
+	})
+
+	t.Run("sessions left unused are ended to make room", func(t *testing.T) {
+		st := newSessionStore(tower.StartingBalance, tower.DefaultMaxWager, 2)
+		st.idle = time.Minute
+		srv := httptest.NewServer(st.handler())
+		defer srv.Close()
+		stale, kept := create(t, srv, "").ID, create(t, srv, "").ID
+		var e apiError
+		do(t, srv, "POST", "/sessions", "", http.StatusServiceUnavailable, &e)
+
+		st.mu.Lock()
+		st.sessions[stale].used = time.Now().Add(-2 * time.Minute)
+		st.mu.Unlock()
+		create(t, srv, "")
+		do(t, srv, "GET", "/sessions/"+stale, "", http.StatusNotFound, &e)
+		do(t, srv, "GET", "/sessions/"+kept, "", http.StatusOK, nil)
+
+		st.mu.Lock()
+		st.sessions[kept].used = time.Now().Add(-2 * time.Minute)
+		st.mu.Unlock()
+		do(t, srv, "GET", "/sessions/"+kept, "", http.StatusNotFound, &e)
+	})
+
+	t.Run("moves on one session at once are played one at a time", func(t *testing.T) {
+		srv := newServer(t, 10)
+		id := create(t, srv, `{"seed": 8}`).ID
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, path := range []string{"bet", "hit", "hit", "cash-out"} {
+					resp, err := srv.Client().Post(srv.URL+"/sessions/"+id+"/"+path, "application/json", nil)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					resp.Body.Close()
+					if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict && resp.StatusCode != http.StatusBadRequest {
+						t.Errorf("%s: got status %d", path, resp.StatusCode)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		var history []tower.Record
+		do(t, srv, "GET", "/sessions/"+id+"/history", "", http.StatusOK, &history)
+		var st sessionState
+		do(t, srv, "GET", "/sessions/"+id, "", http.StatusOK, &st)
+		balance := tower.StartingBalance
+		for _, rec := range history {
+			balance += rec.Payout - rec.Wager
+		}
+		if st.State == tower.StatePlaying || st.State == tower.StateGameOver && !st.Result.Bust {
+			balance -= st.Wager // a round still on the table
+		}
+		if st.Balance != balance {
+			t.Fatalf("want a balance of %d after %d rounds, got %d", balance, len(history), st.Balance)
+		}
+	})
+}
+
+func TestLiveTable(t *testing.T) {
+	newServer := func(t *testing.T) *httptest.Server {
+		t.Helper()
+		st := newSessionStore(tower.StartingBalance, tower.DefaultMaxWager, 10)
+		st.allowSeed = true
+		srv := httptest.NewServer(st.handler())
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	create := func(t *testing.T, srv *httptest.Server, body string) sessionState {
+		t.Helper()
+		resp, err := srv.Client().Post(srv.URL+"/sessions", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var st sessionState
+		if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}
+	post := func(t *testing.T, srv *httptest.Server, path, body string) {
+		t.Helper()
+		resp, err := srv.Client().Post(srv.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: got status %d", path, resp.StatusCode)
+		}
+	}
+	dial := func(t *testing.T, srv *httptest.Server, path string) *wsConn {
+		t.Helper()
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := "dGhlIHNhbXBsZSBub25jZQ=="
+		fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n",
+			path, srv.Listener.Addr(), key)
+		r := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(r, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+			t.Fatalf("want a WebSocket, got %s %v", resp.Status, resp.Header)
+		}
+		c := &wsConn{conn: conn, r: r, client: true}
+		t.Cleanup(func() { c.close() })
+		return c
+	}
+	next := func(t *testing.T, c *wsConn) liveMessage {
+		t.Helper()
+		c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b, err := c.read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m liveMessage
+		if err := json.Unmarshal(b, &m); err != nil {
+			t.Fatalf("%v: %s", err, b)
+		}
+		return m
+	}
+	// untilState() returns the messages up to the next state.
+	untilState := func(t *testing.T, c *wsConn) []liveMessage {
+		t.Helper()
+		msgs := []liveMessage{}
+		for {
+			m := next(t, c)
+			msgs = append(msgs, m)
+			if m.Kind == liveState || m.Kind == liveError {
+				return msgs
+			}
+		}
+	}
+
+	t.Run("every move is streamed to everyone at the table", func(t *testing.T) {
+		srv := newServer(t)
+		st := create(t, srv, `{"seed": 9}`)
+		early := dial(t, srv, "/tables/"+st.Table+"/live")
+		player := dial(t, srv, "/sessions/"+st.ID+"/live")
+		for _, c := range []*wsConn{early, player} {
+			if m := next(t, c); m.Kind != liveSnapshot || m.Table == nil || m.Table.ID != "" || m.Table.Table != st.Table {
+				t.Fatalf("want a snapshot without the session ID, got %+v", m)
+			}
+		}
+
+		// the same moves on a game of its own tell the same events
+		g, err := tower.NewGame(tower.WithSeed(9))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.SetOutput(io.Discard)
+		want := []tower.Event{}
+		tower.WithTableEvent(func(e tower.Event) { want = append(want, e) })(&g)
+
+		post(t, srv, "/sessions/"+st.ID+"/bet", `{"wager": 30}`)
+		pressKeys(&g, tower.Action{Kind: tower.ActionBet, Wager: 30})
+		got := untilState(t, early)
+		if !reflect.DeepEqual(untilState(t, player), got) {
+			t.Fatalf("the player and the spectator were told different things")
+		}
+
+		late := dial(t, srv, "/tables/"+st.Table+"/live")
+		if m := next(t, late); m.Kind != liveSnapshot || !reflect.DeepEqual(m.Table, got[len(got)-1].Table) {
+			t.Fatalf("want joining mid-round to get the table as it stands, got %+v", m)
+		}
+
+		for g.State() == tower.StatePlaying {
+			player.writeText([]byte(`{"action":"hit"}`))
+			pressKeys(&g, tower.Action{Kind: tower.ActionHit})
+			msgs := untilState(t, early)
+			if !reflect.DeepEqual(untilState(t, late), msgs) || !reflect.DeepEqual(untilState(t, player), msgs) {
+				t.Fatalf("the table was told different things")
+			}
+			got = append(got, msgs...)
+		}
+		events := []tower.Event{}
+		for _, m := range got {
+			if m.Kind != liveState {
+				events = append(events, m.Event)
+			}
+		}
+		if !reflect.DeepEqual(events, want) {
+			t.Fatalf("want the game's events %+v, got %+v", want, events)
+		}
+		last := got[len(got)-1].Table
+		if v := g.View(false); !reflect.DeepEqual(last.View, v) || last.Event != tableEvent(&g) {
+			t.Fatalf("want the game's state %+v, got %+v", v, last)
+		}
+	})
+
+	t.Run("only the seated player can play", func(t *testing.T) {
+		srv := newServer(t)
+		st := create(t, srv, "")
+		watcher := dial(t, srv, "/tables/"+st.Table+"/live")
+		player := dial(t, srv, "/sessions/"+st.ID+"/live")
+		next(t, watcher)
+		next(t, player)
+
+		watcher.writeText([]byte(`{"action":"bet"}`))
+		if m := next(t, watcher); m.Kind != liveError || m.Error != errSpectator.Error() {
+			t.Fatalf("want a spectator's move turned down, got %+v", m)
+		}
+		player.writeText([]byte(`{"action":"hit"}`))
+		if m := next(t, player); m.Kind != liveError || !strings.Contains(m.Error, "isn't allowed") {
+			t.Fatalf("want hitting before a bet turned down, got %+v", m)
+		}
+		player.writeText([]byte(`{"action":"fold"}`))
+		if m := next(t, player); m.Kind != liveError || !strings.Contains(m.Error, "can't read") {
+			t.Fatalf("want nonsense turned down, got %+v", m)
+		}
+		player.writeText([]byte(`{"action":"bet","wager":30}`))
+		for _, c := range []*wsConn{watcher, player} {
+			if m := next(t, c); m.Kind != tower.EventBet || m.Wager != 30 {
+				t.Fatalf("want the bet, and nothing of what was turned down, got %+v", m)
+			}
+		}
+	})
+
+	t.Run("ending the session closes the table", func(t *testing.T) {
+		srv := newServer(t)
+		st := create(t, srv, "")
+		watcher := dial(t, srv, "/tables/"+st.Table+"/live")
+		next(t, watcher)
+
+		req, _ := http.NewRequest("DELETE", srv.URL+"/sessions/"+st.ID, nil)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		watcher.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := watcher.read(); err != io.EOF {
+			t.Fatalf("want the connection closed, got %v", err)
+		}
+	})
+
+	t.Run("a full table turns watchers away and frees the seats of those who leave", func(t *testing.T) {
+		st := newSessionStore(tower.StartingBalance, tower.DefaultMaxWager, 10)
+		srv := httptest.NewServer(st.handler())
+		defer srv.Close()
+		table := create(t, srv, "").Table
+		for i := 0; i < maxWatchers; i++ {
+			resp, err := srv.Client().Get(srv.URL + "/tables/" + table + "/live")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+		conns := []*wsConn{}
+		for i := 0; i < maxWatchers; i++ {
+			conns = append(conns, dial(t, srv, "/tables/"+table+"/live"))
+		}
+
+		resp, err := srv.Client().Get(srv.URL + "/tables/" + table + "/live")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("want a full table turning watchers away, got status %d", resp.StatusCode)
+		}
+		s := st.getTable(table)
+		s.mu.Lock()
+		n := len(s.watchers)
+		s.mu.Unlock()
+		if n != maxWatchers {
+			t.Fatalf("want %d watchers, got %d", maxWatchers, n)
+		}
+		conns[0].close()
+		for deadline := time.Now().Add(5 * time.Second); n == maxWatchers && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			s.mu.Lock()
+			n = len(s.watchers)
+			s.mu.Unlock()
+		}
+		if n != maxWatchers-1 {
+			t.Fatalf("want the seat of a watcher who left freed, got %d watchers", n)
+		}
+	})
+
+	t.Run("watchers that stop answering pings are dropped", func(t *testing.T) {
+		interval, timeout := wsPingInterval, wsReadTimeout
+		wsPingInterval, wsReadTimeout = 20*time.Millisecond, 100*time.Millisecond
+		defer func() { wsPingInterval, wsReadTimeout = interval, timeout }()
+		store := newSessionStore(tower.StartingBalance, tower.DefaultMaxWager, 10)
+		srv := httptest.NewServer(store.handler())
+		defer srv.Close()
+		st := create(t, srv, "")
+		alive := dial(t, srv, "/tables/"+st.Table+"/live")
+		dead := dial(t, srv, "/tables/"+st.Table+"/live")
+		next(t, alive)
+		next(t, dead)
+
+		// alive reads, and so answers pings; dead never reads again
+		done := make(chan error, 1)
+		go func() {
+			_, err := alive.read()
+			done <- err
+		}()
+		time.Sleep(300 * time.Millisecond)
+		s := store.getTable(st.Table)
+		s.mu.Lock()
+		n := len(s.watchers)
+		s.mu.Unlock()
+		if n != 1 {
+			t.Fatalf("want only the watcher answering pings left, got %d watchers", n)
+		}
+		post(t, srv, "/sessions/"+st.ID+"/bet", "")
+		if err := <-done; err != nil {
+			t.Fatalf("want the watcher answering pings told of the bet, got %v", err)
+		}
+	})
+
+	t.Run("a table needs a WebSocket and a table", func(t *testing.T) {
+		srv := newServer(t)
+		st := create(t, srv, "")
+		for path, status := range map[string]int{
+			"/tables/" + st.Table + "/live": http.StatusBadRequest,
+			"/tables/nope/live":             http.StatusNotFound,
+			"/tables/" + st.ID + "/live":    http.StatusNotFound,
+		} {
+			resp, err := srv.Client().Get(srv.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != status {
+				t.Errorf("%s: want status %d, got %d", path, status, resp.StatusCode)
+			}
+		}
+	})
+}
+
+func TestTableCmd(t *testing.T) {
+	// alone() plays seed's first round alone at wager, with keys, and returns the result.
+	alone := func(t *testing.T, wager int, keys ...string) tower.Result {
+		t.Helper()
+		var r tower.Result
+		g, err := tower.NewGame(tower.WithSeed(4), tower.WithRoundEnd(func(res tower.Result) { r = res }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.SetOutput(io.Discard)
+		g.SetWager(wager)
+		for _, k := range keys {
+			g.Input(k)
+		}
+		return r
+	}
+
+	t.Run("players take turns on the same tower", func(t *testing.T) {
+		players, err := newTablePlayers("ann, ben, bot=hit-to:2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tb, err := tower.NewTable(tower.WithSeed(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range players {
+			tb.Sit(p.name, tower.StartingBalance)
+		}
+		out := &bytes.Buffer{}
+		scr := newScreen(out, "")
+		tb.SetOutput(scr)
+		// ann raises to 30 and bets, ben sits out, ann cashes out on row 1 while the bot rides
+		keys := lineReader{bufio.NewReader(strings.NewReader("+\nz\ns\nx\n"))}
+
+		if code := playTable(tb, players, scr, keys, nil, tower.StrategyRand(4), false, 0); code != 0 {
+			t.Fatalf("want exit status 0, got %d", code)
+		}
+		seats := tb.Seats()
+		ann, bot := alone(t, 30, "z", "x"), alone(t, 15, "z", "z", "x")
+		if seats[0].Balance != tower.StartingBalance-30+ann.Payout || seats[0].Result == nil || *seats[0].Result != ann {
+			t.Fatalf("want ann paid %+v, got %+v", ann, seats[0])
+		}
+		if seats[1].Balance != tower.StartingBalance || seats[1].Result != nil {
+			t.Fatalf("want ben sat out, got %+v", seats[1])
+		}
+		if seats[2].Balance != tower.StartingBalance-15+bot.Payout || seats[2].Result == nil || seats[2].Result.Row != 2 {
+			t.Fatalf("want the bot to ride to row 2 and be paid %+v, got %+v", bot, seats[2])
+		}
+		if !strings.Contains(out.String(), fmt.Sprintf("ann: Paid %d for row 1", ann.Payout)) || !strings.Contains(out.String(), "bot: hit") {
+			t.Fatalf("want every move shown, got:\n%s", out)
+		}
+	})
+
+	t.Run("strategies bet what they can afford, and the game ends when no one can", func(t *testing.T) {
+		players, err := newTablePlayers("a=hit-to:8,b=hit-to:8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tb, err := tower.NewTable(tower.WithSeed(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range players {
+			i, _ := tb.Sit(p.name, 120)
+			tb.Bet(i, 90)
+		}
+		out := &bytes.Buffer{}
+		scr := newScreen(out, "")
+		tb.SetOutput(scr)
+		done := make(chan int)
+		go func() {
+			done <- playTable(tb, players, scr, blockingReader{}, nil, tower.StrategyRand(4), false, 0)
+		}()
+		select {
+		case code := <-done:
+			if code != 0 {
+				t.Fatalf("want exit status 0, got %d", code)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("the game didn't end")
+		}
+		for _, s := range tb.Seats() {
+			if s.Wager > s.Balance || s.Balance >= tower.WagerStep && s.Wager == 0 {
+				t.Errorf("want every bet dropped to what's affordable, got %+v", s)
+			}
+		}
+		if !strings.Contains(out.String(), "No one can cover their bet.") {
+			t.Fatalf("want the game to end once no one can bet, got:\n%s", out)
+		}
+	})
+
+	t.Run("bad players", func(t *testing.T) {
+		for _, list := range []string{"ann,,ben", "ann=nonsense", "=ev"} {
+			if _, err := newTablePlayers(list); err == nil {
+				t.Errorf("%q: want an error", list)
+			}
+		}
+	})
+}
+
+// TestBotProcess isn't a test: it's the bot TestTournament and TestBot start. It plays the
+// strategy $FORTUNES_TOWER_TEST_BOT names, answers nonsense or stays silent. It quits after
+// $FORTUNES_TOWER_TEST_BOT_ROUNDS rounds if that's set, and bets $FORTUNES_TOWER_TEST_BOT_BET if that is.
+func TestBotProcess(t *testing.T) {
+	play := os.Getenv("FORTUNES_TOWER_TEST_BOT")
+	if play == "" {
+		return
+	}
+	rounds, _ := strconv.Atoi(os.Getenv("FORTUNES_TOWER_TEST_BOT_ROUNDS"))
+	bet, _ := strconv.Atoi(os.Getenv("FORTUNES_TOWER_TEST_BOT_BET"))
+	s, _ := tower.NewStrategy(play)
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		var msg botMessage
+		json.Unmarshal(in.Bytes(), &msg)
+		switch {
+		case play == "silent":
+			continue
+		case s == nil:
+			fmt.Println("fold!")
+			continue
+		case msg.Event == "round_end":
+			if rounds--; rounds == 0 {
+				fmt.Println(`{"action":"quit"}`)
+				continue
+			}
+		}
+		a, _ := s.Decide(msg.View, nil)
+		if a.Kind == tower.ActionBet && bet != 0 {
+			a.Wager = bet
+		}
+		b, _ := json.Marshal(a)
+		fmt.Println(string(b))
+	}
+	os.Exit(0)
+}
+
+// blockingReader never returns a key, like a player who has walked away.
+type blockingReader struct{}
+
+func (blockingReader) readKey() (string, error) {
+	select {}
+}
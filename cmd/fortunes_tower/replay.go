@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// replayCmd() checks the rounds in a round log against the engine and steps through them,
+// and returns the exit status: 1 if --check finds a round the engine plays differently.
+func replayCmd(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	round := fs.Int("round", 0, "only replay the nth round in the log, from 1 (default every round)")
+	check := fs.Bool("check", false, "only check every round against the engine, don't step through them")
+	colorFlag := fs.String("color", "auto", "color cards: auto, always or never")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: fortunes_tower replay [flags] <log.jsonl>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	color, err := colorMode(*colorFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	replays, err := loadReplays(fs.Arg(0), *round, tower.WithColor(color))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if *check {
+		return checkReplays(os.Stdout, replays)
+	}
+
+	scr := newScreen(os.Stdout, "")
+	keys, restore := newKeyReader(os.Stdin)
+	defer restore()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	code := stepThrough(replays, scr, keys, sigs)
+	restore()
+	return code
+}
+
+// loadReplays() reads the round log at path, keeping only the nth round if n isn't 0.
+func loadReplays(path string, n int, opts ...tower.Option) ([]*tower.Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	recs, err := tower.ReadLog(f)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > len(recs) {
+		return nil, fmt.Errorf("%s has %d rounds, can't replay round %d", path, len(recs), n)
+	}
+	if n > 0 {
+		recs = recs[n-1 : n]
+	}
+	if len(recs) == 0 {
+		return nil, fmt.Errorf("%s has no rounds", path)
+	}
+
+	replays := make([]*tower.Replay, len(recs))
+	for i, rec := range recs {
+		if replays[i], err = tower.NewReplay(rec, opts...); err != nil {
+			return nil, fmt.Errorf("round %d in %s: %w", rec.Round, path, err)
+		}
+	}
+	return replays, nil
+}
+
+// checkReplays() verifies every replay, printing the rounds that don't match, and returns the exit status.
+func checkReplays(w io.Writer, replays []*tower.Replay) int {
+	bad := 0
+	for i, r := range replays {
+		if err := r.Verify(); err != nil {
+			fmt.Fprintf(w, "round %d (log line %d): %v\n", r.Record().Round, i+1, err)
+			bad++
+		}
+	}
+	fmt.Fprintf(w, "%d of %d rounds match the engine\n", len(replays)-bad, len(replays))
+	if bad > 0 {
+		return 1
+	}
+	return 0
+}
+
+// stepThrough() shows the replays one dealt row at a time until the player quits, input ends
+// or a signal arrives, and returns the exit status like play().
+func stepThrough(replays []*tower.Replay, scr *screen, keys keyReader, sigs <-chan os.Signal) int {
+	input := make(chan keyEvent)
+	go func() {
+		for {
+			key, err := keys.readKey()
+			input <- keyEvent{key, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	verdicts := make([]string, len(replays))
+	i, step := 0, 1
+	for {
+		r := replays[i]
+		if verdicts[i] == "" {
+			verdicts[i] = "Engine matches the record."
+			if err := r.Verify(); err != nil {
+				verdicts[i] = err.Error()
+			}
+		}
+		showStep(scr, r, i, len(replays), step, verdicts[i])
+		scr.flush()
+
+		select {
+		case sig := <-sigs:
+			fmt.Fprintln(scr.out)
+			if s, ok := sig.(syscall.Signal); ok {
+				return 128 + int(s)
+			}
+			return 1
+		case k := <-input:
+			if k.err == io.EOF {
+				return 0
+			}
+			if k.err != nil {
+				fmt.Fprintln(os.Stderr, k.err)
+				return 1
+			}
+			switch k.key {
+			case "q", "quit":
+				return 0
+			case "z", "n", "":
+				switch {
+				case step < r.Len():
+					step++
+				case i < len(replays)-1:
+					i, step = i+1, 1
+				}
+			case "b", "p":
+				switch {
+				case step > 1:
+					step--
+				case i > 0:
+					i--
+					step = replays[i].Len()
+				}
+			}
+		}
+	}
+}
+
+// showStep() draws the tower after step hits of replay i, with the round's outcome on its last step.
+func showStep(scr *screen, r *tower.Replay, i, n, step int, verdict string) {
+	rec := r.Record()
+	fmt.Fprintf(scr, "Round %d of %d (seed %d, round seed %d), row %d of %d\n", i+1, n, rec.Seed, rec.RoundSeed, step, r.Len())
+	fmt.Fprintf(scr, "Deck: %s, bet %d\n\n", rec.Deck.Name, rec.Wager)
+
+	g, err := r.Game(step)
+	if err != nil && !errors.Is(err, tower.ErrReplayMismatch) {
+		fmt.Fprintln(scr, err)
+		return
+	}
+	g.SetOutput(scr)
+	g.PrintTower()
+
+	if step == r.Len() {
+		switch {
+		case rec.Bust:
+			fmt.Fprintf(scr, "Recorded: BUST on row %d\n", rec.Row)
+		case rec.Jackpot:
+			fmt.Fprintf(scr, "Recorded: JACKPOT, won %d\n", rec.Payout)
+		default:
+			fmt.Fprintf(scr, "Recorded: cashed out row %d for %d (x%d)\n", rec.Row, rec.Payout, rec.Multiplier)
+		}
+	}
+	fmt.Fprintln(scr, verdict)
+	fmt.Fprintln(scr, `"z" next row, "b" back, "q" quit`)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// clearScreen moves the cursor home and clears to the end of the screen.
+const clearScreen = "\033[H\033[J"
+
+// screen collects everything the game prints between two inputs into one frame.
+// On a terminal each frame is drawn over the last, under a fixed header.
+// Anywhere else frames are appended, so piped output reads like a log.
+type screen struct {
+	out    io.Writer
+	redraw bool
+	header string
+	frame  bytes.Buffer
+}
+
+// newScreen() creates a screen that redraws in place if out is a terminal.
+func newScreen(out io.Writer, header string) *screen {
+	s := &screen{out: out, redraw: tower.IsTerminal(out), header: header}
+	if !s.redraw {
+		io.WriteString(out, header)
+	}
+	return s
+}
+
+// Write() adds p to the current frame.
+func (s *screen) Write(p []byte) (int, error) {
+	return s.frame.Write(p)
+}
+
+// flush() draws the current frame and starts a new one.
+func (s *screen) flush() error {
+	defer s.frame.Reset()
+	if s.redraw {
+		if _, err := io.WriteString(s.out, clearScreen+s.header); err != nil {
+			return err
+		}
+	}
+	_, err := s.out.Write(s.frame.Bytes())
+	return err
+}
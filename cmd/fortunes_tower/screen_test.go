@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScreen(t *testing.T) {
+	t.Run("frames are appended when output isn't a terminal", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		s := newScreen(out, "Seed: 1\n")
+
+		s.Write([]byte("frame 1\n"))
+		s.flush()
+		s.Write([]byte("frame 2\n"))
+		s.flush()
+
+		want := "Seed: 1\nframe 1\nframe 2\n"
+		if out.String() != want {
+			t.Fatalf("want %q, got %q", want, out.String())
+		}
+	})
+
+	t.Run("frames are drawn over each other on a terminal", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		s := newScreen(out, "Seed: 1\n")
+		s.redraw = true
+
+		s.Write([]byte("frame 1\n"))
+		s.flush()
+		out.Reset()
+		s.Write([]byte("frame 2\n"))
+		s.flush()
+
+		want := clearScreen + "Seed: 1\nframe 2\n"
+		if out.String() != want {
+			t.Fatalf("want %q, got %q", want, out.String())
+		}
+	})
+}
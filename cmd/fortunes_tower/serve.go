@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// maxHistory is how many rounds a session keeps for its history, the oldest are dropped first.
+const maxHistory = 1000
+
+// maxBody is the largest request body the server reads.
+const maxBody = 1 << 16
+
+// defaultSessionIdle is how long a session can go unused before it's ended, unless --session-idle says otherwise.
+const defaultSessionIdle = 30 * time.Minute
+
+var (
+	errTooManySessions = errors.New("too many sessions, try again later")
+	errSeedNotAllowed  = errors.New("this server doesn't let sessions choose their seed")
+)
+
+// serveCmd() serves the game as a JSON API over HTTP until a signal arrives, and returns the exit status.
+func serveCmd(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	balance := fs.Int("balance", tower.StartingBalance, "money every session starts with")
+	maxBet := fs.Int("max-bet", tower.DefaultMaxWager, "table maximum bet, a multiple of 15")
+	maxSessions := fs.Int("max-sessions", 1000, "sessions kept at once, new ones are turned down past this")
+	idle := fs.Duration("session-idle", defaultSessionIdle, "end sessions the player hasn't used for this long, 0 keeps them until they're deleted")
+	allowSeed := fs.Bool("allow-seed", false, "let new sessions choose their seed, for testing: whoever knows the seed knows every card")
+	fs.Parse(args)
+
+	st := newSessionStore(*balance, *maxBet, *maxSessions)
+	st.idle, st.allowSeed = *idle, *allowSeed
+	// check the table once, before anyone sits at it
+	if _, err := st.newGame(newSessionRequest{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	srv := &http.Server{Handler: st.handler(), ReadHeaderTimeout: 10 * time.Second}
+	srv.RegisterOnShutdown(st.closeAll) // Shutdown() doesn't wait for WebSockets
+	errs := make(chan error, 1)
+	go func() { errs <- srv.Serve(l) }()
+	fmt.Printf("Serving on %s\n", l.Addr())
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-errs:
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	case <-sigs:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// session is one player's game on the server, at a table others can watch. A Game isn't safe
+// to use from more than one goroutine, so everything about a session is guarded by its own lock.
+type session struct {
+	mu       sync.Mutex
+	id       string // secret, whoever has it plays
+	table    string // public, whoever has it watches
+	g        tower.Game
+	counts   bool // show the unseen cards
+	history  []tower.Record
+	last     *tower.Result     // how the last round ended, nil before the first
+	events   []tower.Event     // what the move being played has done so far
+	watchers map[*watcher]bool // the connections watching the table
+	ended    bool              // the session was removed, no one else can watch
+
+	used time.Time // when the player last used the session, guarded by the store's lock
+}
+
+// sessionStore holds the server's sessions, by ID and by table. Its lock only guards the maps
+// and when each session was last used, so sessions don't wait on each other.
+type sessionStore struct {
+	mu          sync.Mutex
+	sessions    map[string]*session
+	tables      map[string]*session
+	balance     int
+	maxBet      int
+	maxSessions int
+	idle        time.Duration // sessions unused for longer are ended, 0 keeps them
+	allowSeed   bool          // new sessions can choose their seed
+}
+
+// newSessionStore() creates a store whose sessions start with balance, at a table with a
+// maximum bet of maxBet. It holds at most maxSessions sessions, and ends those unused for defaultSessionIdle.
+func newSessionStore(balance, maxBet, maxSessions int) *sessionStore {
+	return &sessionStore{sessions: map[string]*session{}, tables: map[string]*session{}, balance: balance, maxBet: maxBet, maxSessions: maxSessions, idle: defaultSessionIdle}
+}
+
+// newSessionRequest is the body of a request for a new session. Everything is optional.
+type newSessionRequest struct {
+	Seed   *int64 `json:"seed"` // random if not given, only allowed with --allow-seed
+	Deck   string `json:"deck"`
+	Rows   int    `json:"rows"`
+	Counts bool   `json:"counts"` // show how many of each card are left
+}
+
+// betRequest is the body of a bet. A wager of 0 bets the last wager again.
+type betRequest struct {
+	Wager int `json:"wager"`
+}
+
+// sessionState is a session as the API shows it: what the player can see, what the last move
+// did, the burned cards of the last row dealt and how the last round ended. The ID is left out
+// for spectators.
+//
+// Event is "start" before the first round, then "deal", "gate", "bust", "complete" and
+// "round_end", the same as a bot is sent.
+type sessionState struct {
+	ID    string `json:"id,omitempty"`
+	Table string `json:"table"`
+	Event string `json:"event"`
+	tower.View
+	Burns  []tower.Burn  `json:"burns,omitempty"`
+	Result *tower.Result `json:"result,omitempty"`
+}
+
+// apiError is the body of every error response.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// handler() returns the API:
+//
+//	POST   /sessions              start a session, see newSessionRequest
+//	GET    /sessions/{id}         the session's state
+//	DELETE /sessions/{id}         end the session
+//	POST   /sessions/{id}/bet     bet and deal the first row, see betRequest
+//	POST   /sessions/{id}/hit     deal the next row, or collect a round that's over
+//	POST   /sessions/{id}/cash-out cash out, or collect a round that's over
+//	GET    /sessions/{id}/history the session's rounds as round log records, oldest first
+//	GET    /sessions/{id}/live    a WebSocket to watch the table and play on, see live()
+//	GET    /tables/{table}/live   a WebSocket to watch the table
+//
+// Moves answer with the state after them.
+func (st *sessionStore) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /sessions", st.create)
+	mux.HandleFunc("GET /sessions/{id}", st.withSession(func(s *session, _ *http.Request) (any, error) {
+		return s.state(), nil
+	}))
+	mux.HandleFunc("DELETE /sessions/{id}", st.remove)
+	mux.HandleFunc("POST /sessions/{id}/bet", st.withSession(func(s *session, r *http.Request) (any, error) {
+		var req betRequest
+		if err := readBody(r, &req); err != nil {
+			return nil, err
+		}
+		return s.move(tower.Action{Kind: tower.ActionBet, Wager: req.Wager})
+	}))
+	mux.HandleFunc("POST /sessions/{id}/hit", st.withSession(func(s *session, _ *http.Request) (any, error) {
+		return s.move(tower.Action{Kind: tower.ActionHit})
+	}))
+	mux.HandleFunc("POST /sessions/{id}/cash-out", st.withSession(func(s *session, _ *http.Request) (any, error) {
+		return s.move(tower.Action{Kind: tower.ActionCashOut})
+	}))
+	mux.HandleFunc("GET /sessions/{id}/history", st.withSession(func(s *session, _ *http.Request) (any, error) {
+		return append([]tower.Record{}, s.history...), nil
+	}))
+	mux.HandleFunc("GET /sessions/{id}/live", st.live(true))
+	mux.HandleFunc("GET /tables/{table}/live", st.live(false))
+	return mux
+}
+
+// newGame() creates the game for a new session.
+func (st *sessionStore) newGame(req newSessionRequest) (tower.Game, error) {
+	deck := tower.DiamondDeck
+	if req.Deck != "" {
+		d, err := tower.DeckByName(req.Deck)
+		if err != nil {
+			return tower.Game{}, err
+		}
+		deck = d
+	}
+	rows := tower.DefaultRows
+	if req.Rows != 0 {
+		rows = req.Rows
+	}
+	opts := []tower.Option{tower.WithDeck(deck), tower.WithRows(rows), tower.WithMaxWager(st.maxBet), tower.WithBalance(st.balance)}
+	if req.Seed != nil {
+		opts = append(opts, tower.WithSeed(*req.Seed))
+	}
+	return tower.NewGame(opts...)
+}
+
+// create() starts a session.
+func (st *sessionStore) create(w http.ResponseWriter, r *http.Request) {
+	var req newSessionRequest
+	if err := readBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Seed != nil && !st.allowSeed {
+		writeError(w, http.StatusBadRequest, errSeedNotAllowed)
+		return
+	}
+	g, err := st.newGame(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	g.SetOutput(io.Discard)
+
+	s, err := st.add(g, req.Counts)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Location", "/sessions/"+s.id)
+	writeJSON(w, http.StatusCreated, s.state())
+}
+
+// add() stores a new session playing g, first ending the sessions that have gone unused for too long.
+func (st *sessionStore) add(g tower.Game, counts bool) (*session, error) {
+	s := &session{g: g, counts: counts, watchers: map[*watcher]bool{}}
+	// the hooks run during moves, while s is locked
+	tower.WithRoundEnd(func(r tower.Result) { s.last = &r })(&s.g)
+	tower.WithRoundLog(func(rec tower.Record) {
+		s.history = append(s.history, rec)
+		if len(s.history) > maxHistory {
+			s.history = s.history[len(s.history)-maxHistory:]
+		}
+	})(&s.g)
+	tower.WithTableEvent(func(e tower.Event) { s.events = append(s.events, e) })(&s.g)
+
+	st.mu.Lock()
+	idle := st.evictIdle()
+	defer func() {
+		st.mu.Unlock()
+		end(idle)
+	}()
+	if len(st.sessions) >= st.maxSessions {
+		return nil, errTooManySessions
+	}
+	for s.id == "" || st.sessions[s.id] != nil {
+		s.id = newSessionID()
+	}
+	for s.table == "" || st.tables[s.table] != nil {
+		s.table = newSessionID()
+	}
+	s.used = time.Now()
+	st.sessions[s.id] = s
+	st.tables[s.table] = s
+	return s, nil
+}
+
+// get() returns the session called id, or nil, and notes that its player used it.
+// A session that has gone unused for too long is ended instead.
+func (st *sessionStore) get(id string) *session {
+	st.mu.Lock()
+	s := st.sessions[id]
+	if s != nil && st.isIdle(s) {
+		st.delete(s)
+		st.mu.Unlock()
+		end([]*session{s})
+		return nil
+	}
+	if s != nil {
+		s.used = time.Now()
+	}
+	st.mu.Unlock()
+	return s
+}
+
+// getTable() returns the session at table, or nil.
+func (st *sessionStore) getTable(table string) *session {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if s := st.tables[table]; s != nil && !st.isIdle(s) {
+		return s
+	}
+	return nil
+}
+
+// remove() ends a session, and closes the connections watching it.
+func (st *sessionStore) remove(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	st.mu.Lock()
+	s := st.sessions[id]
+	if s != nil {
+		st.delete(s)
+	}
+	st.mu.Unlock()
+	if s == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no session %q", id))
+		return
+	}
+	end([]*session{s})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isIdle() reports whether s has gone unused for too long. st must be locked.
+func (st *sessionStore) isIdle(s *session) bool {
+	return st.idle > 0 && time.Since(s.used) > st.idle
+}
+
+// evictIdle() takes the sessions that have gone unused for too long out of the store, and
+// returns them to be ended once st is unlocked. st must be locked.
+func (st *sessionStore) evictIdle() []*session {
+	idle := []*session{}
+	for _, s := range st.sessions {
+		if st.isIdle(s) {
+			st.delete(s)
+			idle = append(idle, s)
+		}
+	}
+	return idle
+}
+
+// delete() takes s out of the store. st must be locked.
+func (st *sessionStore) delete(s *session) {
+	delete(st.sessions, s.id)
+	delete(st.tables, s.table)
+}
+
+// end() ends sessions taken out of the store, and closes the connections watching them.
+func end(sessions []*session) {
+	for _, s := range sessions {
+		s.mu.Lock()
+		s.ended = true
+		s.dropAll()
+		s.mu.Unlock()
+	}
+}
+
+// closeAll() closes every connection watching a table, for when the server shuts down.
+func (st *sessionStore) closeAll() {
+	st.mu.Lock()
+	sessions := make([]*session, 0, len(st.sessions))
+	for _, s := range st.sessions {
+		sessions = append(sessions, s)
+	}
+	st.mu.Unlock()
+	end(sessions)
+}
+
+// withSession() returns a handler that runs f on the session named in the path, with the
+// session locked, and answers with what f returns.
+func (st *sessionStore) withSession(f func(s *session, r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		s := st.get(id)
+		if s == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no session %q", id))
+			return
+		}
+		s.mu.Lock()
+		v, err := f(s, r)
+		s.mu.Unlock()
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+// move() plays a, the same as a bot's answer, tells the table what it did, and returns the
+// state after it.
+func (s *session) move(a tower.Action) (sessionState, error) {
+	if err := pressKeys(&s.g, a); err != nil {
+		s.events = nil
+		return sessionState{}, err
+	}
+	s.broadcast()
+	return s.state(), nil
+}
+
+// state() returns the session as the API shows it.
+func (s *session) state() sessionState {
+	v := s.g.View(s.counts)
+	event := tableEvent(&s.g)
+	if v.State == tower.StateBetting && s.last == nil {
+		event = "start"
+	}
+	return sessionState{ID: s.id, Table: s.table, Event: event, View: v, Burns: s.g.Burns(v.Row), Result: s.last}
+}
+
+// errorStatus() returns the HTTP status for a move turned down with err.
+func errorStatus(err error) int {
+	if errors.Is(err, tower.ErrBadAction) || errors.Is(err, tower.ErrRoundInProgress) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// readBody() reads the request's JSON body into v. An empty body leaves v as it is.
+func readBody(r *http.Request, v any) error {
+	d := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBody))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("can't read the request: %w", err)
+	}
+	return nil
+}
+
+// writeJSON() answers with v as JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError() answers with err as an apiError.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
+
+// newSessionID() returns a random session ID, hard to guess, as it's all that's needed to play.
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// simulateCmd() plays rounds headless with a strategy and prints what happened, and returns the exit status.
+func simulateCmd(args []string) int {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	strategy := fs.String("strategy", "hit-to:4", "how to play: "+strings.Join(tower.StrategyNames, ", "))
+	rounds := fs.Int("rounds", 100000, "rounds to play")
+	seed := fs.Int64("seed", 1, "seed the rounds are shuffled from")
+	workers := fs.Int("workers", runtime.NumCPU(), "rounds played at once; results don't depend on it")
+	bet := fs.Int("bet", tower.WagerStep, "bet for every round, a multiple of 15")
+	deckName := fs.String("deck", tower.DiamondDeck.Name, "deck to deal from: "+deckNames())
+	rows := fs.Int("rows", tower.DefaultRows, fmt.Sprintf("tower height, gate row included (%d-%d)", tower.MinRows, tower.MaxRows))
+	fs.Parse(args)
+
+	deck, err := tower.DeckByName(*deckName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	s, err := tower.NewStrategy(*strategy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	sim, err := tower.Simulate(tower.SimConfig{
+		Deck:     deck,
+		Rows:     *rows,
+		Wager:    *bet,
+		Rounds:   *rounds,
+		Seed:     *seed,
+		Workers:  *workers,
+		Strategy: s,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	fmt.Printf("Strategy %s, %s deck, %d rows, bet %d, seed %d\n", *strategy, deck.Name, *rows, *bet, *seed)
+	if err := sim.WriteReport(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
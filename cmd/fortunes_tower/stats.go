@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// statsCmd() prints a profile's lifetime statistics, or resets the profile, and returns the exit status.
+func statsCmd(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	profileName := fs.String("profile", defaultProfile, "profile to show")
+	reset := fs.Bool("reset", false, "wipe the profile's statistics and restore the starting balance")
+	fs.Parse(args)
+
+	if *reset {
+		p := tower.NewProfile(*profileName)
+		if err := saveProfile(&p); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Profile %q has been reset.\n", *profileName)
+		return 0
+	}
+
+	p, err := loadProfile(*profileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Print(p.String())
+	return 0
+}
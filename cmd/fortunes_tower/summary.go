@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// summary tallies a session for the report printed on exit.
+type summary struct {
+	startBalance  int
+	rounds        int
+	biggestPayout int
+}
+
+// add() records the result of a round.
+func (s *summary) add(r tower.Result) {
+	s.rounds++
+	if r.Payout > s.biggestPayout {
+		s.biggestPayout = r.Payout
+	}
+}
+
+// report() describes the session, given the balance it ended on.
+func (s *summary) report(balance int) string {
+	net := balance - s.startBalance
+	result := fmt.Sprintf("won %d", net)
+	if net < 0 {
+		result = fmt.Sprintf("lost %d", -net)
+	}
+	return fmt.Sprintf("Rounds played: %d\nNet: %s\nBiggest payout: %d\n", s.rounds, result, s.biggestPayout)
+}
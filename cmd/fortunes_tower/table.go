@@ -0,0 +1,310 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// tablePlayer is a seat at a shared tower, played from the keyboard, or by s if it isn't nil.
+type tablePlayer struct {
+	name string
+	s    tower.Strategy
+}
+
+// tableCmd() plays a shared tower in the terminal, the players taking turns at the keyboard,
+// and returns the exit status. It plays with its own money: profiles aren't touched.
+func tableCmd(args []string) int {
+	fs := flag.NewFlagSet("table", flag.ExitOnError)
+	playersFlag := fs.String("players", "player 1,player 2", "players at the table, separated by commas. name=strategy lets a built-in strategy play: "+strings.Join(tower.StrategyNames, ", "))
+	seed := fs.Int64("seed", 0, "seed for every shuffle, and for the random strategy (default random)")
+	balance := fs.Int("balance", tower.StartingBalance, "money every player starts with")
+	bet := fs.Int("bet", tower.WagerStep, "bet every player starts with, a multiple of 15")
+	maxBet := fs.Int("max-bet", tower.DefaultMaxWager, "table maximum bet, a multiple of 15")
+	deckName := fs.String("deck", tower.DiamondDeck.Name, "deck to deal from: "+deckNames())
+	rows := fs.Int("rows", tower.DefaultRows, fmt.Sprintf("tower height, gate row included (%d-%d)", tower.MinRows, tower.MaxRows))
+	counts := fs.Bool("counts", false, "let the strategies see how many of each card are left")
+	delay := fs.Duration("delay", time.Second/2, "pause after a strategy's move")
+	colorFlag := fs.String("color", "auto", "color cards: auto, always or never")
+	fs.Parse(args)
+
+	color, err := colorMode(*colorFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	deck, err := tower.DeckByName(*deckName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	players, err := newTablePlayers(*playersFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	opts := []tower.Option{tower.WithMaxWager(*maxBet), tower.WithDeck(deck), tower.WithRows(*rows), tower.WithColor(color)}
+	if flagSet(fs, "seed") {
+		opts = append(opts, tower.WithSeed(*seed))
+	}
+	t, err := tower.NewTable(opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	for _, p := range players {
+		i, err := t.Sit(p.name, *balance)
+		if err == nil {
+			err = t.Bet(i, *bet)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	scr := newScreen(os.Stdout, fmt.Sprintf("Seed: %d\n", t.Seed()))
+	t.SetOutput(scr)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	keys, restore := newKeyReader(os.Stdin)
+	defer restore() // also runs if the game panics
+	code := playTable(t, players, scr, keys, sigs, tower.StrategyRand(t.Seed()), *counts, *delay)
+	restore()
+	for _, s := range t.Seats() {
+		net := s.Balance - *balance
+		fmt.Printf("%s: %d (%+d)\n", s.Name, s.Balance, net)
+	}
+	return code
+}
+
+// newTablePlayers() seats the players in list, separated by commas. A player given as
+// name=strategy is played by that built-in strategy.
+func newTablePlayers(list string) ([]tablePlayer, error) {
+	players := []tablePlayer{}
+	for _, spec := range strings.Split(list, ",") {
+		name, strategy, _ := strings.Cut(strings.TrimSpace(spec), "=")
+		if name == "" {
+			return nil, fmt.Errorf("player %q has no name", spec)
+		}
+		p := tablePlayer{name: name}
+		if strategy != "" {
+			s, err := tower.NewStrategy(strategy)
+			if err != nil {
+				return nil, fmt.Errorf("player %s: %w", name, err)
+			}
+			p.s = s
+		}
+		players = append(players, p)
+	}
+	return players, nil
+}
+
+// playTable() runs the shared tower until a player quits, input ends, no player can place their
+// bet or a signal arrives, and returns the exit status, the same as play(). Before a round each
+// player sets their bet in turn, then each row every player still riding says whether to ride on
+// or cash out. rng and counts are passed on to the strategies, and delay is the pause after each
+// of their moves.
+func playTable(t *tower.Table, players []tablePlayer, scr *screen, keys keyReader, sigs <-chan os.Signal, rng *rand.Rand, counts bool, delay time.Duration) int {
+	input := make(chan keyEvent)
+	go func() {
+		for {
+			key, err := keys.readKey()
+			input <- keyEvent{key, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	// wait() draws the frame and waits for a key, or the pause after a strategy's move if
+	// strategy is true. It returns the key, or false and the exit status if the game is over.
+	wait := func(strategy bool) (string, int, bool) {
+		scr.flush()
+		pause := time.After(delay)
+		if !strategy {
+			pause = nil
+		}
+		select {
+		case sig := <-sigs:
+			fmt.Fprintln(scr.out)
+			if s, ok := sig.(syscall.Signal); ok {
+				return "", 128 + int(s), false
+			}
+			return "", 1, false
+		case <-pause:
+			return "", 0, true
+		case k := <-input:
+			if k.err == io.EOF {
+				return "", 0, false
+			}
+			if k.err != nil {
+				fmt.Fprintln(os.Stderr, k.err)
+				return "", 1, false
+			}
+			if k.key == "q" || k.key == "quit" {
+				return "", 0, false
+			}
+			return k.key, 0, true
+		}
+	}
+
+	for {
+		if t.State() != tower.StatePlaying {
+			for i, p := range players {
+				if code, ok := tableBet(t, i, p, scr, wait); !ok {
+					return code
+				}
+			}
+			err := t.Deal()
+			if errors.Is(err, tower.ErrNoBets) && brokeTable(t, players) {
+				fmt.Fprintln(scr, "No one can cover their bet.")
+				scr.flush()
+				return 0
+			}
+			if err != nil {
+				fmt.Fprintln(scr, err)
+			}
+			continue
+		}
+
+		i := nextRider(t)
+		p := players[i]
+		printTable(t, scr)
+		before := t.Seats()
+		key, code, ok := "", 0, true
+		if p.s != nil {
+			v, _ := t.View(i, counts)
+			a, err := p.s.Decide(v, rng)
+			if err != nil {
+				fmt.Fprintln(scr, err)
+				scr.flush()
+				return 1
+			}
+			key = "x"
+			if a.Kind == tower.ActionHit {
+				key = "z"
+			}
+			fmt.Fprintf(scr, "%s: %s\n", p.name, a)
+			_, code, ok = wait(true)
+		} else {
+			v, _ := t.View(i, false)
+			fmt.Fprintf(scr, "%s, cashing out pays %d: z to ride on, x to cash out\n", p.name, v.CashOut)
+			key, code, ok = wait(false)
+		}
+		if !ok {
+			return code
+		}
+		var err error
+		switch key {
+		case "z":
+			err = t.Hit(i)
+		case "x":
+			err = t.CashOut(i)
+		}
+		if err != nil {
+			fmt.Fprintln(scr, err)
+		}
+		for j, s := range t.Seats() {
+			if s.Result != nil && before[j].Result == nil {
+				fmt.Fprintf(scr, "%s: ", s.Name)
+				printResult(scr, *s.Result)
+			}
+		}
+	}
+}
+
+// tableBet() lets player i set their bet for the next round from the keyboard, and returns false
+// and the exit status if the game is over. Strategies keep the bet they started with, or the most
+// they can still afford once they can't cover it, and sit out once they can't afford any.
+func tableBet(t *tower.Table, i int, p tablePlayer, scr *screen, wait func(bool) (string, int, bool)) (int, bool) {
+	if p.s != nil {
+		if s := t.Seats()[i]; s.Wager > s.Balance {
+			t.Bet(i, s.Balance/tower.WagerStep*tower.WagerStep)
+		}
+		return 0, true
+	}
+
+	for {
+		printTable(t, scr)
+		s := t.Seats()[i]
+		fmt.Fprintf(scr, "%s, money %d, bet %d: z to bet, + or - to change it, s to sit out\n", s.Name, s.Balance, s.Wager)
+		key, code, ok := wait(false)
+		if !ok {
+			return code, false
+		}
+		var err error
+		switch key {
+		case "z":
+			return 0, true
+		case "s":
+			t.Bet(i, 0)
+			return 0, true
+		case "+":
+			err = t.Bet(i, s.Wager+tower.WagerStep)
+		case "-":
+			err = t.Bet(i, s.Wager-tower.WagerStep)
+		}
+		if err != nil {
+			fmt.Fprintln(scr, err)
+		}
+	}
+}
+
+// nextRider() returns the first seat still riding that hasn't asked for the next row.
+func nextRider(t *tower.Table) int {
+	for i, s := range t.Seats() {
+		if s.Riding && !s.Ready {
+			return i
+		}
+	}
+	return -1
+}
+
+// brokeTable() reports whether no one at t can place a bet: strategies can't cover their own,
+// and the players at the keyboard can't afford the smallest.
+func brokeTable(t *tower.Table, players []tablePlayer) bool {
+	for i, s := range t.Seats() {
+		if players[i].s != nil && s.Wager > 0 && s.Wager <= s.Balance {
+			return false
+		}
+		if players[i].s == nil && s.Balance >= tower.WagerStep {
+			return false
+		}
+	}
+	return true
+}
+
+// printTable() prints the tower, if there's one up, and where every player stands.
+func printTable(t *tower.Table, scr *screen) {
+	if t.State() != tower.StateBetting {
+		t.PrintTower()
+	}
+	for _, s := range t.Seats() {
+		status := "sitting out"
+		switch {
+		case s.Riding && s.Ready:
+			status = "riding on"
+		case s.Riding:
+			status = "riding"
+		case s.Result != nil && s.Result.Bust:
+			status = fmt.Sprintf("bust on row %d", s.Result.Row)
+		case s.CashOutRow > 0:
+			status = fmt.Sprintf("cashed out on row %d", s.CashOutRow)
+		case t.State() == tower.StateBetting && s.Wager > 0:
+			status = "waiting for the deal"
+		}
+		fmt.Fprintf(scr, "%s: money %d, bet %d, %s\n", s.Name, s.Balance, s.Wager, status)
+	}
+}
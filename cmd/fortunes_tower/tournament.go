@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mikzorz/fortunes_tower/tower"
+)
+
+// tournamentCmd() plays strategies against each other on the same cards, prints a leaderboard,
+// and returns the exit status.
+func tournamentCmd(args []string) int {
+	fs := flag.NewFlagSet("tournament", flag.ExitOnError)
+	strategies := fs.String("strategies", "hit-to:2,hit-to:3,hit-to:4,cash-out-at:60,random", "built-in strategies to enter, separated by commas: "+strings.Join(tower.StrategyNames, ", "))
+	bots := []string{}
+	fs.Func("bot", "enter a bot program as `name=command`, can be given more than once", func(s string) error {
+		bots = append(bots, s)
+		return nil
+	})
+	botTimeout := fs.Duration("bot-timeout", defaultBotTimeout, "how long a bot has to answer")
+	sessions := fs.Int("sessions", 100, "sessions every strategy plays")
+	rounds := fs.Int("rounds", 100, "rounds in a session, unless the money runs out first")
+	bankroll := fs.Int("bankroll", tower.StartingBalance, "money at the start of every session")
+	bet := fs.Int("bet", tower.WagerStep, "bet at the start of every session, a multiple of 15")
+	seed := fs.Int64("seed", 1, "seed the sessions are shuffled from")
+	deckName := fs.String("deck", tower.DiamondDeck.Name, "deck to deal from: "+deckNames())
+	rows := fs.Int("rows", tower.DefaultRows, fmt.Sprintf("tower height, gate row included (%d-%d)", tower.MinRows, tower.MaxRows))
+	counts := fs.Bool("counts", false, "let the strategies see how many of each card are left")
+	rank := fs.String("rank", tower.RankProfit, "rank by "+strings.Join(tower.RankNames, ", "))
+	csvPath := fs.String("csv", "", "also write the leaderboard as CSV to this file")
+	fs.Parse(args)
+
+	deck, err := tower.DeckByName(*deckName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	names := []string{}
+	if *strategies != "" {
+		names = strings.Split(*strategies, ",")
+	}
+	entrants, closeBots, err := newEntrants(names, bots, *botTimeout)
+	defer closeBots()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	standings, err := tower.Tournament(tower.TournamentConfig{
+		Deck:          deck,
+		Rows:          *rows,
+		Wager:         *bet,
+		Seed:          *seed,
+		Sessions:      *sessions,
+		SessionRounds: *rounds,
+		Bankroll:      *bankroll,
+		Counts:        *counts,
+	}, entrants)
+	if err == nil {
+		err = tower.SortStandings(standings, *rank)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	fmt.Printf("%d sessions of %d rounds from %d gold, bet %d, %s deck, %d rows, seed %d\n",
+		*sessions, *rounds, *bankroll, *bet, deck.Name, *rows, *seed)
+	if err := tower.WriteLeaderboard(os.Stdout, standings); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *csvPath != "" {
+		if err := writeFileAtomic(*csvPath, func(f *os.File) error { return tower.WriteStandingsCSV(f, standings) }); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// newEntrants() enters the built-in strategies called names, and starts the bots, each given as
+// name=command and given timeout to answer. The returned func stops the bots, it's never nil.
+func newEntrants(names, bots []string, timeout time.Duration) ([]tower.Entrant, func(), error) {
+	started := []*bot{}
+	closeBots := func() {
+		for _, b := range started {
+			b.close()
+		}
+	}
+
+	entrants := []tower.Entrant{}
+	for _, name := range names {
+		s, err := tower.NewStrategy(strings.TrimSpace(name))
+		if err != nil {
+			return nil, closeBots, err
+		}
+		entrants = append(entrants, tower.Entrant{Name: strings.TrimSpace(name), Strategy: s})
+	}
+	for _, spec := range bots {
+		name, command, ok := strings.Cut(spec, "=")
+		if !ok || name == "" {
+			return nil, closeBots, fmt.Errorf("bot %q: want name=command", spec)
+		}
+		b, err := startBot(command, timeout, nil)
+		if err != nil {
+			return nil, closeBots, fmt.Errorf("bot %s: %w", name, err)
+		}
+		started = append(started, b)
+		entrants = append(entrants, tower.Entrant{Name: name, Strategy: b})
+	}
+	return entrants, closeBots, nil
+}
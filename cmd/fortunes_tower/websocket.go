@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The WebSocket opcodes, RFC 6455 section 5.2.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// wsGUID is mixed into the handshake key to show the server speaks WebSocket.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsWriteTimeout is how long a message can take to send before the connection is given up on.
+const wsWriteTimeout = 10 * time.Second
+
+// How often the server pings a connection, and how long it waits to hear anything back, a pong
+// included, before giving the connection up for dead. They're variables so tests can shorten them.
+var (
+	wsPingInterval = 30 * time.Second
+	wsReadTimeout  = 60 * time.Second
+)
+
+// wsConn is a WebSocket connection, enough of RFC 6455 for messages of JSON: messages up to maxBody
+// bytes, pings answered, no extensions. Reading is for one goroutine, writing is safe from any.
+type wsConn struct {
+	conn        net.Conn
+	r           *bufio.Reader
+	client      bool          // mask what's sent, as a client must, and expect what's read unmasked
+	readTimeout time.Duration // how long to wait for each frame, 0 for as long as it takes
+	wmu         sync.Mutex
+}
+
+// upgradeWebSocket() takes the request's connection over as a WebSocket. If it can't, it has
+// answered the request with why.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		err := errors.New("want a WebSocket")
+		writeError(w, http.StatusBadRequest, err)
+		return nil, err
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		err := errors.New("want WebSocket version 13")
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeError(w, http.StatusUpgradeRequired, err)
+		return nil, err
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		err := errors.New("no Sec-WebSocket-Key")
+		writeError(w, http.StatusBadRequest, err)
+		return nil, err
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		err := errors.New("can't take over the connection")
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, err
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + wsAccept(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, r: rw.Reader, readTimeout: wsReadTimeout}, nil
+}
+
+// wsAccept() returns the Sec-WebSocket-Accept that answers key.
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerHas() reports whether the comma separated header name has token in it, ignoring case.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// read() returns the next message, answering pings on the way. It returns io.EOF once the other
+// end closes the connection, and an error if it sends nothing for the read timeout.
+func (c *wsConn) read() ([]byte, error) {
+	msg := []byte{}
+	for {
+		if c.readTimeout > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		}
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case wsPing:
+			if err := c.write(wsPong, payload); err != nil {
+				return nil, err
+			}
+		case wsPong:
+		case wsClose:
+			c.write(wsClose, payload[:min(len(payload), 2)])
+			return nil, io.EOF
+		case wsText, wsBinary, wsContinuation:
+			if msg = append(msg, payload...); len(msg) > maxBody {
+				return nil, fmt.Errorf("websocket: message over %d bytes", maxBody)
+			}
+			if fin {
+				return msg, nil
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %#x", op)
+		}
+	}
+}
+
+// readFrame() reads one frame.
+func (c *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	h := make([]byte, 2, 8)
+	if _, err := io.ReadFull(c.r, h); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = h[0]&0x80 != 0, h[0]&0x0f
+	if masked := h[1]&0x80 != 0; masked == c.client {
+		return false, 0, nil, errors.New("websocket: frame masked the wrong way")
+	}
+	n := uint64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		h = h[:2]
+		if _, err := io.ReadFull(c.r, h); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(h))
+	case 127:
+		h = h[:8]
+		if _, err := io.ReadFull(c.r, h); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(h)
+	}
+	if n > maxBody {
+		return false, 0, nil, fmt.Errorf("websocket: frame over %d bytes", maxBody)
+	}
+
+	var mask [4]byte
+	if !c.client {
+		if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if !c.client {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// write() sends payload as one frame.
+func (c *wsConn) write(op byte, payload []byte) error {
+	frame := []byte{0x80 | op}
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// writeText() sends msg as a text message.
+func (c *wsConn) writeText(msg []byte) error {
+	return c.write(wsText, msg)
+}
+
+// close() says goodbye with a normal closure and closes the connection.
+func (c *wsConn) close() error {
+	c.write(wsClose, []byte{0x03, 0xe8}) // 1000, normal closure
+	return c.conn.Close()
+}
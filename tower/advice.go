@@ -0,0 +1,92 @@
+package tower
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Advice compares cashing out now with hitting.
+//
+// Hit is the expected payout of hitting and then playing on the best way, worked out by a
+// search: every way the next rows could be dealt from the cards the player can't see, choosing
+// again after each one, with the gate card and the jackpot played by the engine's rules.
+// Exact says whether the search got to the bottom of the tower. Otherwise it stopped a few rows
+// ahead, and Hit is what it found so far, which hitting is worth at least: enough to say hit
+// if it's more than cashing out pays, but not to say cash out if it isn't.
+type Advice struct {
+	CashOut float64 // what cashing out now pays
+	Hit     float64 // expected payout of hitting, then playing on the best way
+	Exact   bool    // Hit is exact, not only the least hitting is worth
+	Odds    Odds    // odds of the next deal
+}
+
+// ShouldHit() reports whether hitting is expected to pay more than cashing out.
+func (a Advice) ShouldHit() bool {
+	return a.Hit > a.CashOut
+}
+
+// ShouldCashOut() reports whether cashing out is sure to pay at least as much as hitting is expected to.
+// If neither it nor ShouldHit() is true, the search couldn't see far enough ahead to tell.
+func (a Advice) ShouldCashOut() bool {
+	return a.Exact && !a.ShouldHit()
+}
+
+// String() explains the advice.
+func (a Advice) String() string {
+	b := &strings.Builder{}
+	worth := "worth"
+	if !a.Exact {
+		worth = "worth at least"
+	}
+	switch {
+	case a.ShouldHit():
+		fmt.Fprintf(b, "Advice: hit. Cashing out pays %.0f, hitting is %s %.1f on average.\n", a.CashOut, worth, a.Hit)
+	case a.ShouldCashOut():
+		fmt.Fprintf(b, "Advice: cash out. Cashing out pays %.0f, hitting is only worth %.1f on average.\n", a.CashOut, a.Hit)
+	default:
+		fmt.Fprintf(b, "No advice: cashing out pays %.0f and hitting is worth at least %.1f on average, "+
+			"but the rest of the tower is too far ahead to tell if it's worth more.\n", a.CashOut, a.Hit)
+	}
+	fmt.Fprintf(b, "Row %d busts %.1f%% of the time, is saved by the gate card %.1f%% and raises the multiplier %.1f%%",
+		a.Odds.Row, 100*a.Odds.Bust, 100*a.Odds.GateSave, 100*a.Odds.Multiplier)
+	if a.Odds.Jackpot > 0 {
+		fmt.Fprintf(b, ", the jackpot comes up %.1f%% of the time", 100*a.Odds.Jackpot)
+	}
+	b.WriteString(".\n")
+	return b.String()
+}
+
+// Advise() compares cashing out with hitting. It returns false if there is no choice to make:
+// before the wager is paid, or after a game over.
+func (g *Game) Advise() (Advice, bool) {
+	return g.advise(math.Inf(1))
+}
+
+// advise() is Advise(), but stops looking further ahead once hitting is worth more than enough.
+func (g *Game) advise(enough float64) (Advice, bool) {
+	if g.State() != StatePlaying {
+		return Advice{}, false
+	}
+
+	m := float64(g.multiplier)
+	s := newSearch(g.deckDef, g.rows, searchLimit)
+	hit, exact := s.hit(s.position(g), enough/m)
+	return Advice{
+		CashOut: float64(g.cashOutValue()),
+		Hit:     hit * m,
+		Exact:   exact,
+		Odds:    g.Odds(),
+	}, true
+}
+
+// cashOutValue() returns what cashing out would pay now.
+func (g *Game) cashOutValue() int {
+	if g.curRow == 0 || g.bust {
+		return 0
+	}
+	if g.isJackpot() {
+		return g.getJackpotValue() * g.multiplier
+	}
+	return g.getRowValue(g.lastDealtRow()) * g.multiplier
+}
@@ -0,0 +1,94 @@
+package tower
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ColorMode decides whether cards are printed with ANSI colors.
+type ColorMode int
+
+const (
+	// ColorAuto colors output written to a terminal, unless NO_COLOR is set.
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
+)
+
+// ANSI escape codes used when printing in color.
+const (
+	ansiReset   = "\033[0m"
+	ansiBold    = "\033[1m"
+	ansiHero    = "\033[1;30;43m" // black on yellow
+	ansiGate    = "\033[1;7m"     // bold, reversed
+	ansiBurned  = "\033[1;97;41m" // white on red
+	ansiMulti   = "\033[1;93m"    // bright yellow
+	ansiJackpot = "\033[1;5;92m"  // blinking bright green
+)
+
+// cardColors are cycled through by card value, starting at 1.
+var cardColors = []string{
+	"\033[31m", "\033[32m", "\033[33m", "\033[34m", "\033[35m", "\033[36m", "\033[97m",
+	"\033[91m", "\033[92m", "\033[94m", "\033[95m", "\033[96m",
+}
+
+// ParseColorMode() parses the value of a --color flag: auto, always or never.
+func ParseColorMode(s string) (ColorMode, error) {
+	switch strings.ToLower(s) {
+	case "auto":
+		return ColorAuto, nil
+	case "always":
+		return ColorAlways, nil
+	case "never":
+		return ColorNever, nil
+	}
+	return ColorAuto, fmt.Errorf("tower: unknown color mode %q, want auto, always or never", s)
+}
+
+// WithColor() sets whether the Print methods use color. Defaults to ColorAuto.
+func WithColor(mode ColorMode) Option {
+	return func(g *Game) error {
+		g.color = mode
+		return nil
+	}
+}
+
+// SetColor() changes whether the Print methods use color.
+func (g *Game) SetColor(mode ColorMode) {
+	g.color = mode
+}
+
+// colorOn() resolves the game's ColorMode against its output.
+func (g *Game) colorOn() bool {
+	switch g.color {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return ColorEnabled(g.out)
+}
+
+// ColorEnabled() reports whether ColorAuto would color output written to w:
+// w must be a terminal and NO_COLOR must be unset or empty.
+func ColorEnabled(w io.Writer) bool {
+	return os.Getenv("NO_COLOR") == "" && IsTerminal(w)
+}
+
+// paint() wraps s in the ANSI code if color is on.
+func (g *Game) paint(s, code string) string {
+	if !g.colorOn() || code == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// cardColor() returns the color of a card with value v.
+func cardColor(v int) string {
+	if v == Hero {
+		return ansiHero
+	}
+	return cardColors[(v-1)%len(cardColors)]
+}
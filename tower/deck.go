@@ -0,0 +1,133 @@
+package tower
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Deck describes the cards a round is dealt from: Copies[i] of each of Values[i], plus Heroes.
+type Deck struct {
+	Name   string `json:"name"`
+	Values []int  `json:"values"`
+	Copies []int  `json:"copies"`
+	Heroes int    `json:"heroes"`
+}
+
+// DiamondDeck is the standard Fable 2 deck described in the README.
+var DiamondDeck = Deck{Name: "diamond", Values: []int{1, 2, 3, 4, 5, 6, 7}, Copies: []int{8, 8, 8, 8, 8, 8, 8}, Heroes: 4}
+
+// Decks lists the built-in decks, in the order they're offered to the player.
+var Decks = []Deck{DiamondDeck}
+
+// DeckByName() returns the built-in deck called name, ignoring case.
+func DeckByName(name string) (Deck, error) {
+	names := []string{}
+	for _, d := range Decks {
+		if strings.EqualFold(d.Name, name) {
+			return d, nil
+		}
+		names = append(names, d.Name)
+	}
+	return Deck{}, fmt.Errorf("tower: unknown deck %q, want one of %s", name, strings.Join(names, ", "))
+}
+
+// WithDeck() deals every round from d instead of the Diamond Deck.
+func WithDeck(d Deck) Option {
+	return func(g *Game) error {
+		if err := d.Validate(); err != nil {
+			return err
+		}
+		g.deckDef = d
+		return nil
+	}
+}
+
+// Size() returns the number of cards in the deck.
+func (d Deck) Size() int {
+	n := d.Heroes
+	for _, c := range d.Copies {
+		n += c
+	}
+	return n
+}
+
+// Validate() checks that the deck has positive, distinct card values, a positive number of copies of each, and
+// enough cards to deal a full tower of MinRows. NewGame() checks it against the chosen height.
+func (d Deck) Validate() error {
+	if len(d.Values) == 0 {
+		return fmt.Errorf("tower: deck %q has no cards", d.Name)
+	}
+	if len(d.Copies) != len(d.Values) {
+		return fmt.Errorf("tower: deck %q has %d card values but %d numbers of copies", d.Name, len(d.Values), len(d.Copies))
+	}
+	if d.Heroes < 0 {
+		return fmt.Errorf("tower: deck %q has a negative number of Heroes", d.Name)
+	}
+	seen := make(map[int]bool)
+	for i, v := range d.Values {
+		if v <= Hero {
+			return fmt.Errorf("tower: deck %q has card value %d, values must be above 0", d.Name, v)
+		}
+		if d.Copies[i] <= 0 {
+			return fmt.Errorf("tower: deck %q has %d copies of %d, it needs at least 1", d.Name, d.Copies[i], v)
+		}
+		if seen[v] {
+			return fmt.Errorf("tower: deck %q has card value %d more than once", d.Name, v)
+		}
+		seen[v] = true
+	}
+	if need := towerSize(MinRows); d.Size() < need {
+		return fmt.Errorf("tower: deck %q has %d cards, a full tower needs %d", d.Name, d.Size(), need)
+	}
+	return nil
+}
+
+// counts() returns how many of each card, Heroes included, a fresh deck holds.
+func (d Deck) counts() map[int]int {
+	c := make(map[int]int)
+	c[Hero] = d.Heroes
+	for i, v := range d.Values {
+		c[v] = d.Copies[i]
+	}
+	return c
+}
+
+// cards() returns the deck in order, values first and Heroes last.
+func (d Deck) cards() []int {
+	cards := make([]int, 0, d.Size())
+	for i, v := range d.Values {
+		for j := 0; j < d.Copies[i]; j++ {
+			cards = append(cards, v)
+		}
+	}
+	for i := 0; i < d.Heroes; i++ {
+		cards = append(cards, Hero)
+	}
+	return cards
+}
+
+// shuffled() returns the deck in the order a round shuffled with seed deals it.
+func (d Deck) shuffled(seed int64) []int {
+	cards := d.cards()
+	rand.New(rand.NewSource(seed)).Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+	return cards
+}
+
+// cardWidth() returns the number of digits in the deck's widest card.
+func (d Deck) cardWidth() int {
+	w := 1
+	for _, v := range d.Values {
+		if n := len(fmt.Sprint(v)); n > w {
+			w = n
+		}
+	}
+	return w
+}
+
+// towerSize() returns the number of cards in a full tower, gate card included.
+func towerSize(rows int) int {
+	return rows * (rows + 1) / 2
+}
@@ -0,0 +1,168 @@
+package tower
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// EdgeTable is how playing by Advise() turns out for a deck and tower height, measured over
+// rounds: how it chose after every row, and its return to player.
+//
+// It isn't a solution to the game. Working out the best play exactly means valuing every tower,
+// gate card and set of unseen cards the rest of a round could reach, and there are far too many
+// of those on a full height tower for that to finish. Near the top, Advise() only sees a few rows
+// ahead, and the return is an estimate from the rounds played, with its standard error.
+type EdgeTable struct {
+	Deck Deck `json:"deck"`
+	Rows int  `json:"rows"`
+
+	// How the advice played after each row, with the gate card face down or used. Index 0 of each
+	// is unused, as there is no choice before row 1 is dealt.
+	GateDown []RowPlay `json:"gate_down"`
+	GateUsed []RowPlay `json:"gate_used"`
+
+	// Rounds is how many rounds were played to measure RTP, the return to player (payouts over
+	// wagers), with StdErr its standard error. Exact is how many choices the search worked out to
+	// the last row, and Unsure how many it couldn't tell, where the table cashed out.
+	Rounds int     `json:"rounds"`
+	RTP    float64 `json:"rtp"`
+	StdErr float64 `json:"std_err"`
+	Exact  int     `json:"exact"`
+	Unsure int     `json:"unsure"`
+}
+
+// RowPlay is how the advice chose after one row, over the rounds an EdgeTable was measured on.
+// Cashing out is counted per WagerStep of the wager.
+type RowPlay struct {
+	Choices int `json:"choices"`  // rounds that got to choose after the row
+	Hits    int `json:"hits"`     // of those, the rounds that hit
+	MaxHit  int `json:"max_hit"`  // the most cashing out paid when it hit anyway, -1 if it never hit
+	MinCash int `json:"min_cash"` // the least cashing out paid when it cashed out, -1 if it never did
+}
+
+// advisedHit() reports whether playing by the advice hits in g's current state. Where the
+// advice can't tell, it cashes out: hitting then, on the Diamond Deck, more than halves the return.
+func (g *Game) advisedHit() (hit bool, a Advice) {
+	a, ok := g.advise(float64(g.cashOutValue()))
+	return ok && a.ShouldHit(), a
+}
+
+// HouseEdge() returns the share of every wager the house keeps on average, playing by the advice.
+func (t EdgeTable) HouseEdge() float64 {
+	return 1 - t.RTP
+}
+
+// MeasureEdge() plays rounds shuffled from seed by the advice, for deck and a tower of rows,
+// measuring its return and noting how it chose after every row.
+func MeasureEdge(deck Deck, rows, rounds int, seed int64) (EdgeTable, error) {
+	if rounds < 1 {
+		return EdgeTable{}, errors.New("tower: measuring the edge needs at least 1 round")
+	}
+	g, err := NewGame(WithDeck(deck), WithRows(rows), WithSeed(seed))
+	if err != nil {
+		return EdgeTable{}, err
+	}
+
+	t := EdgeTable{Deck: deck, Rows: rows, GateDown: make([]RowPlay, rows-1), GateUsed: make([]RowPlay, rows-1), Rounds: rounds}
+	for r := range t.GateDown {
+		t.GateDown[r].MaxHit, t.GateDown[r].MinCash = -1, -1
+		t.GateUsed[r].MaxHit, t.GateUsed[r].MinCash = -1, -1
+	}
+	var total, totalSq float64
+	for i := 0; i < rounds; i++ {
+		g.NewRound()
+		g.balance = g.wager
+		g.Hit()
+		for g.State() == StatePlaying {
+			plays := t.GateUsed
+			if g.GateAvailable() {
+				plays = t.GateDown
+			}
+			play, cash := &plays[g.lastDealtRow()], g.cashOutValue()*WagerStep/g.wager
+			hit, a := g.advisedHit()
+			play.Choices++
+			switch {
+			case a.Exact:
+				t.Exact++
+			case !hit:
+				t.Unsure++
+			}
+			if !hit {
+				if play.MinCash == -1 || cash < play.MinCash {
+					play.MinCash = cash
+				}
+				break
+			}
+			play.Hits++
+			play.MaxHit = max(play.MaxHit, cash)
+			g.Hit()
+		}
+		ret := float64(g.cashOutValue()) / float64(g.wager)
+		total += ret
+		totalSq += ret * ret
+	}
+
+	n := float64(rounds)
+	t.RTP = total / n
+	if rounds > 1 {
+		t.StdErr = math.Sqrt((totalSq/n - t.RTP*t.RTP) / (n - 1))
+	}
+	return t, nil
+}
+
+// WriteTable() writes how the advice played as a table, followed by what it loses on average
+// for every bet up to maxWager.
+func (t EdgeTable) WriteTable(w io.Writer, maxWager int) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "Playing by the advice on the %s deck, %d rows, over %d rounds.\n", t.Deck.Name, t.Rows, t.Rounds)
+	fmt.Fprintf(b, "How often it hit after each row, and what cashing out paid (per %d gold bet) when it hit and when it cashed out.\n", WagerStep)
+	fmt.Fprintf(b, "Where the ranges overlap, the unseen cards decided:\n\n")
+	down, used := []string{}, []string{}
+	width := len("Gate face down")
+	for r := 1; r < t.Rows-1; r++ {
+		down = append(down, rowPlayString(t.GateDown[r]))
+		if r == 1 {
+			used = append(used, "-") // the gate card can't be played before row 2
+		} else {
+			used = append(used, rowPlayString(t.GateUsed[r]))
+		}
+		width = max(width, len(down[r-1]))
+	}
+	fmt.Fprintf(b, "%9s  %-*s  %s\n", "After row", width, "Gate face down", "Gate used")
+	for r := range down {
+		fmt.Fprintf(b, "%9d  %-*s  %s\n", r+1, width, down[r], used[r])
+	}
+	choices := 0
+	for r := range t.GateDown {
+		choices += t.GateDown[r].Choices + t.GateUsed[r].Choices
+	}
+	if choices > 0 {
+		fmt.Fprintf(b, "\n%.1f%% of the choices were worked out exactly, to the last row. %.1f%% were too far from it to tell, and cashed out.\n",
+			100*float64(t.Exact)/float64(choices), 100*float64(t.Unsure)/float64(choices))
+	}
+
+	fmt.Fprintf(b, "\nEstimated return to player %.2f%% ± %.2f%%, a house edge of %.2f%%.\n", 100*t.RTP, 100*t.StdErr, 100*t.HouseEdge())
+	fmt.Fprintf(b, "Payouts grow with the bet, so the edge is the same for every bet, and so is the share lost:\n\n")
+	fmt.Fprintf(b, "%4s  %12s\n", "Bet", "Average loss")
+	for bet := WagerStep; bet <= maxWager; bet += WagerStep {
+		fmt.Fprintf(b, "%4d  %12.2f\n", bet, t.HouseEdge()*float64(bet))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// rowPlayString() describes how a row was played for WriteTable().
+func rowPlayString(r RowPlay) string {
+	switch {
+	case r.Choices == 0:
+		return "never reached"
+	case r.Hits == 0:
+		return fmt.Sprintf("cashed out at %d and up", r.MinCash)
+	case r.Hits == r.Choices:
+		return fmt.Sprintf("always hit, up to %d", r.MaxHit)
+	}
+	return fmt.Sprintf("hit %.0f%% up to %d, cashed out at %d+", 100*float64(r.Hits)/float64(r.Choices), r.MaxHit, r.MinCash)
+}
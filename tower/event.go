@@ -0,0 +1,41 @@
+package tower
+
+// The kinds of Event.
+const (
+	EventBet      = "bet"      // the wager was paid and the gate card dealt face down
+	EventDeal     = "deal"     // a row was dealt
+	EventBurn     = "burn"     // cards on the row just dealt burned
+	EventGate     = "gate"     // the gate card was played in place of a burned card
+	EventBust     = "bust"     // the round ended on a burn
+	EventComplete = "complete" // the last row was dealt, the tower waits to be collected
+	EventCashOut  = "cash_out" // the round was paid out
+)
+
+// Event is something that happened at the table, as it happened. A move can cause several:
+// a hit can deal a row, burn, play the gate card, burn again and bust, in that order.
+type Event struct {
+	Kind   string      `json:"event"`
+	Row    int         `json:"row,omitempty"`
+	Cards  []int       `json:"cards,omitempty"` // the row dealt
+	Burns  []Burn      `json:"burns,omitempty"`
+	Gate   *GateReveal `json:"gate,omitempty"`
+	Wager  int         `json:"wager,omitempty"`  // the bet
+	Result *Result     `json:"result,omitempty"` // how the round ended, on a bust or a cash out
+	Seat   string      `json:"seat,omitempty"`   // whose bet or cash out it was, at a shared Table
+}
+
+// WithTableEvent() calls f with every Event as it happens.
+// It can be given more than once, every f is called in order.
+func WithTableEvent(f func(Event)) Option {
+	return func(g *Game) error {
+		g.onEvent = append(g.onEvent, f)
+		return nil
+	}
+}
+
+// emit() calls the table event hooks with e.
+func (g *Game) emit(e Event) {
+	for _, f := range g.onEvent {
+		f(e)
+	}
+}
@@ -0,0 +1,555 @@
+// Package tower implements the rules of Fortune's Tower, the card game from Fable 2.
+//
+// A Game holds the deck, the tower of dealt cards and the player's money.
+// Frontends drive it with Hit and CashOut (or Input for the classic key bindings)
+// and read it back with Tower, CurRow, Multiplier, Balance and State.
+package tower
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	StateBetting = iota
+	StatePlaying
+	StateGameOver
+)
+
+const (
+	// DefaultRows is the height of a Fable 2 tower, gate row included.
+	DefaultRows = 8
+
+	// MinRows and MaxRows bound the tower height accepted by WithRows().
+	MinRows = 4
+	MaxRows = 12
+)
+
+// Hero is the value of a Hero card. A Hero protects all cards on its row from burning.
+const Hero = 0
+
+// Game contains the deck and the tower
+type Game struct {
+	deck       []int
+	counts     map[int]int
+	deckDef    Deck
+	tower      [][]int
+	rows       int
+	curRow     int
+	balance    int
+	out        io.Writer
+	color      ColorMode
+	state      int
+	wager      int
+	maxWager   int
+	multiplier int
+	bust       bool // the round ended on a burn, rather than by reaching the last row
+	gateRow    int  // where the gate card was played, 0 while it's face down
+	gateIdx    int
+	history    history // what happened this round, for the round log
+
+	onRoundEnd []func(Result)
+	onRecord   []func(Record)
+	onEvent    []func(Event)
+
+	// Every round's shuffle is seeded from src, so one seed replays a whole session.
+	seed      int64
+	seeded    bool // false when src came from WithSource(), its state can't be saved
+	src       rand.Source
+	roundSeed int64
+	rounds    int
+}
+
+// Option configures a Game created by NewGame().
+type Option func(*Game) error
+
+// WithSeed() seeds the game's shuffles. The same seed and the same inputs
+// reproduce the same session.
+func WithSeed(seed int64) Option {
+	return func(g *Game) error {
+		g.seed = seed
+		g.seeded = true
+		g.src = rand.NewSource(seed)
+		return nil
+	}
+}
+
+// WithSource() draws the game's shuffles from src instead of a seeded source.
+func WithSource(src rand.Source) Option {
+	return func(g *Game) error {
+		if src == nil {
+			return errors.New("tower: nil rand.Source")
+		}
+		g.seed = 0
+		g.seeded = false
+		g.src = src
+		return nil
+	}
+}
+
+// WithRows() sets the height of the tower, gate row included, between MinRows and MaxRows.
+// The deck must hold enough cards to deal the whole tower.
+func WithRows(rows int) Option {
+	return func(g *Game) error {
+		if rows < MinRows || rows > MaxRows {
+			return fmt.Errorf("tower: %d rows, want %d to %d", rows, MinRows, MaxRows)
+		}
+		g.rows = rows
+		return nil
+	}
+}
+
+// WithBalance() sets the money the player starts with. Defaults to StartingBalance.
+func WithBalance(b int) Option {
+	return func(g *Game) error {
+		if b < 0 {
+			return fmt.Errorf("tower: negative balance %d", b)
+		}
+		g.balance = b
+		return nil
+	}
+}
+
+// NewGame() creates a new game with a fresh deck, tower and money.
+// Without WithSeed() or WithSource() the game is seeded from the current time.
+func NewGame(opts ...Option) (Game, error) {
+	g := Game{maxWager: DefaultMaxWager, deckDef: DiamondDeck, rows: DefaultRows, balance: StartingBalance}
+	if err := WithSeed(time.Now().UnixNano())(&g); err != nil {
+		return Game{}, err
+	}
+	for _, opt := range opts {
+		if err := opt(&g); err != nil {
+			return Game{}, err
+		}
+	}
+	if need := towerSize(g.rows); g.deckDef.Size() < need {
+		return Game{}, fmt.Errorf("tower: deck %q has %d cards, a %d row tower needs %d", g.deckDef.Name, g.deckDef.Size(), g.rows, need)
+	}
+
+	g.NewRound()
+	g.wager = WagerStep
+	g.out = os.Stdout
+	return g, nil
+}
+
+func (g *Game) NewRound() {
+	g.state = StateBetting
+	g.bust = false
+	g.history = history{}
+	g.multiplier = 1
+	g.NewDeckAndTower()
+	g.curRow = 0
+}
+
+// Set the deck, counts and tower to defaults
+func (g *Game) NewDeckAndTower() {
+	g.counts = g.deckDef.counts()
+
+	g.roundSeed = g.src.Int63()
+	g.rounds++
+	g.deck = g.deckDef.shuffled(g.roundSeed)
+
+	g.tower = make([][]int, g.rows)
+	g.curRow = 0
+	g.gateRow, g.gateIdx = 0, 0
+}
+
+// setDeck() makes cards the rest of the deck, in dealing order, and recounts what's left.
+func (g *Game) setDeck(cards []int) {
+	g.deck = cards
+	g.counts = make(map[int]int)
+	for v := range g.deckDef.counts() {
+		g.counts[v] = 0
+	}
+	for _, v := range cards {
+		g.counts[v]++
+	}
+}
+
+// deal() deals the next row of cards
+func (g *Game) deal() {
+	if g.curRow == 0 {
+		g.balance -= g.wager
+		g.multiplier *= g.wager / WagerStep
+	}
+	if !g.IsGameOver() {
+		g.state = StatePlaying
+		if g.curRow == 0 {
+			g.history.cards = append([]int{}, g.deck...)
+		}
+		for i := 0; i <= g.curRow; i++ {
+			drawnCard := g.deck[0]
+			g.deck = g.deck[1:] // ok because deck never empties completely
+			g.counts[drawnCard]--
+			g.tower[g.curRow] = append(g.tower[g.curRow], drawnCard)
+		}
+		g.history.dealt = append(g.history.dealt, append([]int{}, g.tower[g.curRow]...))
+		if g.curRow == 0 {
+			g.emit(Event{Kind: EventBet, Wager: g.wager})
+		} else {
+			g.emit(Event{Kind: EventDeal, Row: g.curRow, Cards: append([]int{}, g.tower[g.curRow]...)})
+		}
+
+		if g.curRow > 1 {
+			if bust := g.handleBust(); bust {
+				g.bust = true
+				r := g.result(g.curRow, 0, false)
+				g.endRound(r)
+				g.emit(Event{Kind: EventBust, Row: g.curRow, Result: &r})
+				return
+			}
+		}
+		g.checkMulti()
+
+		if g.curRow < g.rows-1 {
+			g.curRow++
+		} else {
+			g.gameOver()
+			g.emit(Event{Kind: EventComplete, Row: g.curRow})
+		}
+	} else if g.bust {
+		g.NewRound()
+	} else {
+		g.cashOut()
+	}
+}
+
+// dealX() repeates deal() x times
+func (g *Game) dealX(x int) {
+	for i := 0; i < x; i++ {
+		g.deal()
+	}
+}
+
+// handleBust() checks the current row for burned cards.
+// If any are burned and the gate card is still face down, the gate replaces the leftmost burned card
+// and the row is checked again against the whole row above, gate card included.
+// If the gate is already used, or the row still burns, gameover, return true.
+// Else, return false
+func (g *Game) handleBust() bool {
+	burns := g.Burns(g.curRow)
+	if len(burns) == 0 {
+		return false
+	}
+	g.history.burns = append(g.history.burns, RowBurns{Row: g.curRow, Burns: burns})
+	g.emit(Event{Kind: EventBurn, Row: g.curRow, Burns: burns})
+
+	if g.GateAvailable() {
+		card := g.tower[0][0]
+		g.tower[g.curRow][burns[0].Index] = card
+		g.tower[0] = []int{}
+		g.gateRow, g.gateIdx = g.curRow, burns[0].Index
+		g.emit(Event{Kind: EventGate, Row: g.curRow, Gate: &GateReveal{Row: g.curRow, Index: burns[0].Index, Card: card}})
+		burns = g.Burns(g.curRow)
+		if len(burns) == 0 {
+			return false
+		}
+		g.history.burns = append(g.history.burns, RowBurns{Row: g.curRow, Burns: burns, AfterGate: true})
+		g.emit(Event{Kind: EventBurn, Row: g.curRow, Burns: burns})
+	}
+
+	g.gameOver()
+	return true
+}
+
+// IsBust() reports whether any card on the last dealt row is burned.
+func (g *Game) IsBust() bool {
+	return len(g.Burns(g.curRow)) > 0
+}
+
+// Burn is a card that shares its value with one of the two cards directly above it.
+type Burn struct {
+	Index int `json:"index"` // position of the burned card on its row
+	Above int `json:"above"` // position of the matching card on the row above
+}
+
+// Burns() compares each card on row with the cards directly above it and returns every match,
+// ordered by Index then Above. A card matching both cards above it appears twice.
+// A Hero anywhere on the row protects the whole row, so a row with a Hero has no burns.
+func (g *Game) Burns(row int) []Burn {
+	if row < 2 || len(g.Protected(row)) > 0 {
+		return nil
+	}
+
+	above := g.tower[row-1]
+	burns := []Burn{}
+	for i, v := range g.tower[row] {
+		// card i sits below cards i-1 and i of the row above
+		for _, j := range []int{i - 1, i} {
+			if j >= 0 && j < len(above) && v == above[j] {
+				burns = append(burns, Burn{Index: i, Above: j})
+			}
+		}
+	}
+	return burns
+}
+
+// Protected() returns the indexes of the cards on row that cannot burn.
+// A Hero protects every card on its own row. A Hero on the row above protects nothing,
+// but it can never burn a card below it either, as only another Hero shares its value.
+func (g *Game) Protected(row int) []int {
+	for _, v := range g.tower[row] {
+		if v == Hero {
+			p := make([]int, len(g.tower[row]))
+			for i := range p {
+				p[i] = i
+			}
+			return p
+		}
+	}
+	return nil
+}
+
+func (g *Game) checkMulti() {
+	if len(g.tower[g.curRow]) > 1 && allSame(g.tower[g.curRow]) {
+		g.multiplier *= len(g.tower[g.curRow])
+		g.history.multiRows = append(g.history.multiRows, g.curRow)
+	}
+}
+
+// allSame() reports whether every card in cards has the same value.
+func allSame(cards []int) bool {
+	for i := 0; i < len(cards)-1; i++ {
+		if cards[i] != cards[i+1] {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Game) getRowValue(row int) int {
+	rv := 0
+	for _, v := range g.tower[row] {
+		rv += v
+	}
+	return rv
+}
+
+func (g *Game) getJackpotValue() int {
+	sum := 0
+	for r := g.rows - 1; r > 0; r-- {
+		sum += g.getRowValue(r)
+	}
+	return sum
+}
+
+// isJackpot() reports whether the last row has been dealt without using the gate card.
+func (g *Game) isJackpot() bool {
+	last := g.rows - 1
+	return g.curRow == last && len(g.tower[last]) == g.rows && len(g.tower[0]) == 1
+}
+
+// cashOut() adds the value of the last dealt row to the player's balance,
+// or the jackpot if the whole tower was dealt without using the gate card.
+// After a bust there is nothing to collect, the round just resets.
+func (g *Game) cashOut() {
+	if g.curRow == 0 && !g.IsGameOver() {
+		return
+	}
+	if g.curRow > 0 && !g.bust {
+		payout := g.cashOutValue()
+		g.balance += payout
+		r := g.result(g.lastDealtRow(), payout, g.isJackpot())
+		g.endRound(r)
+		g.emit(Event{Kind: EventCashOut, Row: r.Row, Result: &r})
+	}
+	g.NewRound()
+}
+
+// lastDealtRow() returns the index of the last row dealt this round.
+// curRow points at the next row to deal, except once the last row is dealt or a row busts.
+func (g *Game) lastDealtRow() int {
+	if len(g.tower[g.curRow]) > 0 {
+		return g.curRow
+	}
+	return g.curRow - 1
+}
+
+// Result describes how a round ended.
+type Result struct {
+	Wager      int  `json:"wager"`
+	Payout     int  `json:"payout"` // amount added to the balance, 0 on a bust
+	Row        int  `json:"row"`    // last row dealt
+	Bust       bool `json:"bust"`
+	Jackpot    bool `json:"jackpot"`
+	GateSave   bool `json:"gate_save"` // the gate card replaced a burned card and the row survived
+	Multiplier int  `json:"multiplier"`
+	MultiRows  int  `json:"multi_rows"` // rows that raised the multiplier
+}
+
+// result() describes the round ending on row.
+func (g *Game) result(row, payout int, jackpot bool) Result {
+	gateRow, _, gateUsed := g.GatePosition()
+	return Result{
+		Wager:      g.wager,
+		Payout:     payout,
+		Row:        row,
+		Bust:       g.bust,
+		Jackpot:    jackpot,
+		GateSave:   gateUsed && !(g.bust && gateRow == row),
+		Multiplier: g.multiplier,
+		MultiRows:  len(g.history.multiRows),
+	}
+}
+
+// WithRoundEnd() calls f with the result of every round as it ends.
+// It can be given more than once, every f is called in order.
+func WithRoundEnd(f func(Result)) Option {
+	return func(g *Game) error {
+		g.onRoundEnd = append(g.onRoundEnd, f)
+		return nil
+	}
+}
+
+func (g *Game) endRound(r Result) {
+	for _, f := range g.onRoundEnd {
+		f(r)
+	}
+	if len(g.onRecord) > 0 {
+		rec := g.record(r)
+		for _, f := range g.onRecord {
+			f(rec)
+		}
+	}
+}
+
+// IsBusted() reports whether the round ended on a burn. It is false once a new round starts.
+func (g *Game) IsBusted() bool {
+	return g.bust
+}
+
+// gameOver() sets game state to StateGameOver.
+func (g *Game) gameOver() {
+	g.state = StateGameOver
+}
+
+func (g *Game) IsGameOver() bool {
+	return g.State() == StateGameOver
+}
+
+// Hit() deals the next row. At the start of a round it pays the wager and
+// deals the gate card along with the first row. After a game over it collects
+// a completed tower, or clears a bust, and returns to betting.
+// A round can't start if the player can't afford the wager.
+func (g *Game) Hit() error {
+	if g.IsGameOver() {
+		g.cashOut()
+		return nil
+	}
+	if g.curRow == 0 {
+		if g.wager > g.balance {
+			return fmt.Errorf("%w: bet is %d, balance is %d", ErrInsufficientBalance, g.wager, g.balance)
+		}
+		g.deal()
+	}
+	g.deal()
+	return nil
+}
+
+// CashOut() ends the round, paying out the last dealt row.
+// After a bust there is nothing to pay, it only returns to betting.
+func (g *Game) CashOut() {
+	g.cashOut()
+}
+
+// Input() reads and processes user input.
+// z deals a new row/confirms
+// x cashes out at the current row if the player has not bust
+// + and - raise and lower the bet by 15 before a round starts
+// It returns an error explaining why an input was rejected.
+func (g *Game) Input(in string) error {
+	in = strings.TrimSuffix(in, "\n")
+	switch in {
+	case "z":
+		return g.Hit()
+	case "x":
+		g.CashOut()
+	case "+":
+		return g.RaiseWager()
+	case "-":
+		return g.LowerWager()
+	}
+	return nil
+}
+
+// Balance() returns player's current cash amount.
+func (g *Game) Balance() int {
+	return g.balance
+}
+
+// State() returns the current game state.
+func (g *Game) State() int {
+	return g.state
+}
+
+// Multiplier() returns the multiplier that will be applied to the next payout.
+func (g *Game) Multiplier() int {
+	return g.multiplier
+}
+
+// CurRow() returns the index of the next row to be dealt.
+// After the last row has been dealt, or after a bust, it is the index of that row.
+func (g *Game) CurRow() int {
+	return g.curRow
+}
+
+// Tower() returns a copy of the tower, one slice per row. Row 0 holds the gate card
+// until it is used. Undealt rows are empty.
+func (g *Game) Tower() [][]int {
+	t := make([][]int, len(g.tower))
+	for i, row := range g.tower {
+		t[i] = append([]int{}, row...)
+	}
+	return t
+}
+
+// GateAvailable() reports whether the gate card is still face down at the top of the tower.
+func (g *Game) GateAvailable() bool {
+	return len(g.tower[0]) > 0
+}
+
+// Seed() returns the seed the game was created with. It is 0 when the game uses a custom rand.Source.
+func (g *Game) Seed() int64 {
+	return g.seed
+}
+
+// RoundSeed() returns the seed the current round's deck was shuffled with.
+func (g *Game) RoundSeed() int64 {
+	return g.roundSeed
+}
+
+// GatePosition() returns the row and index the gate card was played at.
+// ok is false while the gate card is still face down.
+func (g *Game) GatePosition() (row, idx int, ok bool) {
+	return g.gateRow, g.gateIdx, g.gateRow > 0
+}
+
+// Rows() returns the height of the tower, gate row included.
+func (g *Game) Rows() int {
+	return g.rows
+}
+
+// Deck() returns the deck the game deals from.
+func (g *Game) Deck() Deck {
+	return g.deckDef
+}
+
+// Counts() returns how many of each card are left in the deck, keyed by card value.
+// The face down gate card is counted as dealt.
+func (g *Game) Counts() map[int]int {
+	c := make(map[int]int, len(g.counts))
+	for v, n := range g.counts {
+		c[v] = n
+	}
+	return c
+}
+
+// SetOutput() sets where the Print methods write to. Defaults to os.Stdout.
+func (g *Game) SetOutput(w io.Writer) {
+	g.out = w
+}
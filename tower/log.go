@@ -0,0 +1,140 @@
+package tower
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// LogVersion is the version of the Record schema. Fields may be added to Record
+// without changing it; renaming or removing a field, or changing its meaning, bumps it.
+const LogVersion = 1
+
+// Record is one round of a round log, written as one line of JSON.
+//
+// Cards holds the whole deck in dealing order, so Cards with the wager and the row the
+// player stopped at (Row, unless the round bust) replay the round exactly.
+// Seed and RoundSeed replay it through the game's own shuffle instead.
+type Record struct {
+	Version   int   `json:"version"`
+	Round     int   `json:"round"`      // number of the round's shuffle in the session, from 1
+	Seed      int64 `json:"seed"`       // session seed, 0 for games using WithSource()
+	RoundSeed int64 `json:"round_seed"` // the seed the round's deck was shuffled with
+	Deck      Deck  `json:"deck"`
+	Rows      int   `json:"rows"` // tower height, gate row included
+	Cards     []int `json:"cards"`
+
+	// Dealt holds every row as it came off the deck, before the gate card replaced anything.
+	// Dealt[0] is the gate card.
+	Dealt [][]int `json:"dealt"`
+
+	// Gate is where the gate card was played, nil if it stayed face down.
+	Gate *GateReveal `json:"gate,omitempty"`
+
+	// Burns lists the burned cards of every row that burned, and again after the gate card
+	// was played if the row still burned.
+	Burns []RowBurns `json:"burns,omitempty"`
+
+	// MultiplierRows are the rows that raised the multiplier.
+	MultiplierRows []int `json:"multiplier_rows,omitempty"`
+
+	Result
+}
+
+// GateReveal is where the gate card was played and its value.
+type GateReveal struct {
+	Row   int `json:"row"`
+	Index int `json:"index"`
+	Card  int `json:"card"`
+}
+
+// RowBurns are the burned cards found on a row.
+type RowBurns struct {
+	Row       int    `json:"row"`
+	Burns     []Burn `json:"burns"`
+	AfterGate bool   `json:"after_gate"` // the row was checked again after the gate card replaced a burned card
+}
+
+// history is what has happened so far this round.
+type history struct {
+	cards     []int
+	dealt     [][]int
+	burns     []RowBurns
+	multiRows []int
+}
+
+// WithRoundLog() calls f with a Record of every round as it ends.
+// It can be given more than once, every f is called in order.
+func WithRoundLog(f func(Record)) Option {
+	return func(g *Game) error {
+		g.onRecord = append(g.onRecord, f)
+		return nil
+	}
+}
+
+// record() describes the round that ended with r.
+func (g *Game) record(r Result) Record {
+	rec := Record{
+		Version:        LogVersion,
+		Round:          g.rounds,
+		Seed:           g.seed,
+		RoundSeed:      g.roundSeed,
+		Deck:           g.deckDef,
+		Rows:           g.rows,
+		Cards:          g.history.cards,
+		Dealt:          g.history.dealt,
+		Burns:          g.history.burns,
+		MultiplierRows: g.history.multiRows,
+		Result:         r,
+	}
+	if row, idx, ok := g.GatePosition(); ok {
+		rec.Gate = &GateReveal{Row: row, Index: idx, Card: g.history.dealt[0][0]}
+	}
+	return rec
+}
+
+// LogWriter writes Records to a round log, one line of JSON each.
+type LogWriter struct {
+	enc *json.Encoder
+	err error
+}
+
+// NewLogWriter() creates a LogWriter that writes to w.
+func NewLogWriter(w io.Writer) *LogWriter {
+	return &LogWriter{enc: json.NewEncoder(w)}
+}
+
+// Write() writes rec. After the first failed write, nothing more is written.
+// Pass it to WithRoundLog() to log every round.
+func (l *LogWriter) Write(rec Record) {
+	if l.err == nil {
+		l.err = l.enc.Encode(rec)
+	}
+}
+
+// Err() returns the error that stopped the log, if any.
+func (l *LogWriter) Err() error {
+	return l.err
+}
+
+// ReadLog() decodes every Record in a round log.
+func ReadLog(r io.Reader) ([]Record, error) {
+	recs := []Record{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("tower: round log line %d: %w", line, err)
+		}
+		if rec.Version != LogVersion {
+			return nil, fmt.Errorf("tower: round log line %d is version %d, want %d", line, rec.Version, LogVersion)
+		}
+		recs = append(recs, rec)
+	}
+	return recs, sc.Err()
+}
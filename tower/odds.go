@@ -0,0 +1,210 @@
+package tower
+
+import "sort"
+
+// Odds are the chances of what the next deal brings. They are worked out exactly, from every
+// way the cards the player can't see could be dealt: the rest of the deck, plus the gate card
+// while it's face down.
+type Odds struct {
+	Row        int     // the row the next deal deals
+	Bust       float64 // the row burns and the gate card can't save it
+	GateSave   float64 // the row burns and the gate card saves it
+	Multiplier float64 // every card on the row matches, after any gate save
+	Jackpot    float64 // the row is the last one and survives without the gate card
+}
+
+// Safe() returns the chance the next row survives without the gate card.
+func (o Odds) Safe() float64 {
+	return 1 - o.Bust - o.GateSave
+}
+
+// Odds() returns the odds of the next deal. After a game over there is no next deal, and every chance is 0.
+func (g *Game) Odds() Odds {
+	if g.IsGameOver() {
+		return Odds{}
+	}
+
+	// At the start of a round the next deal is the gate card and row 1, which can't burn.
+	row, above := 1, []int(nil)
+	if g.curRow > 0 {
+		row, above = g.curRow, g.tower[g.curRow-1]
+	}
+	unseen := g.Counts()
+	gate := g.GateAvailable()
+	if gate {
+		unseen[g.tower[0][0]]++
+	}
+
+	c := rowOdds(above, unseen, row+1, gate, -1)
+	o := Odds{Row: row, Bust: c.bust, GateSave: c.gateSave, Multiplier: c.multi}
+	if row == g.rows-1 && gate {
+		o.Jackpot = c.safe
+	}
+	return o
+}
+
+// rowChances are the chances of how a row turns out.
+// payout is the expected payout of cashing out straight after the row, per unit of the multiplier
+// it was dealt with: the row's value times any multiplier it raises, 0 if it busts.
+type rowChances struct {
+	safe, gateSave, bust, multi float64
+	payout                      float64
+	work                        int
+}
+
+// rowOdds() works out the chances for a row of n cards dealt below above, drawn without
+// replacement from unseen. If gate is true the gate card is face down, and unseen includes it.
+// If jackpot isn't negative the row is the last one, and surviving it without the gate card
+// pays jackpot, the value of the rows above, on top of the row's own value.
+//
+// The row is dealt one card at a time. Cards of the same value are interchangeable, so all
+// that matters about the cards dealt so far is how many of each value were used, and which
+// card burned first: the gate card replaces it, and can only save the row if no other card
+// burned, or if the gate card is a Hero.
+func rowOdds(above []int, unseen map[int]int, n int, gate bool, jackpot int) rowChances {
+	vals := []int{}
+	total := 0
+	for v, c := range unseen {
+		if c > 0 {
+			vals = append(vals, v)
+			total += c
+		}
+	}
+	sort.Ints(vals)
+	hero := -1
+	if len(vals) > 0 && vals[0] == Hero {
+		hero = 0
+	}
+
+	// burn is -1 if no card burned, -2 if more than one did, else the index of the one burned card.
+	// card is the index in vals of the value of the first burned card.
+	type state struct {
+		used       string
+		burn, card int
+	}
+	states := []state{{used: string(make([]byte, len(vals))), burn: -1}}
+	probs := []float64{1}
+	work := 0
+
+	for i := 0; i < n; i++ {
+		work += len(states)
+		var next []state
+		var nextProbs []float64
+		index := make(map[state]int)
+		left := float64(total - i)
+
+		for k, s := range states {
+			used := []byte(s.used)
+			for t, v := range vals {
+				avail := unseen[v] - int(used[t])
+				if avail <= 0 {
+					continue
+				}
+				used[t]++
+				ns := state{used: string(used), burn: s.burn, card: s.card}
+				switch {
+				case hero >= 0 && used[hero] > 0:
+					ns.burn, ns.card = -1, 0 // a Hero protects the whole row
+				case burnsAt(above, i, v):
+					if s.burn == -1 {
+						ns.burn, ns.card = i, t
+					} else {
+						ns.burn = -2
+					}
+				}
+				used[t]--
+
+				p := probs[k] * float64(avail) / left
+				if j, ok := index[ns]; ok {
+					nextProbs[j] += p
+				} else {
+					index[ns] = len(next)
+					next = append(next, ns)
+					nextProbs = append(nextProbs, p)
+				}
+			}
+		}
+		states, probs = next, nextProbs
+	}
+
+	c := rowChances{work: work + len(states)}
+	left := float64(total - n)
+	for k, s := range states {
+		p := probs[k]
+		used := []byte(s.used)
+		sum := 0
+		for t, u := range used {
+			sum += int(u) * vals[t]
+		}
+		if s.burn == -1 {
+			c.safe += p
+			factor := 1
+			if n > 1 && onlyValue(used) >= 0 {
+				c.multi += p
+				factor = n
+			}
+			if jackpot >= 0 && gate {
+				sum += jackpot
+			}
+			c.payout += p * float64(sum*factor)
+			continue
+		}
+		if !gate || left <= 0 {
+			c.bust += p
+			continue
+		}
+
+		// The gate card is one of the cards left. It replaces the first burned card.
+		for t, v := range vals {
+			avail := unseen[v] - int(used[t])
+			if avail <= 0 {
+				continue
+			}
+			pg := p * float64(avail) / left
+			saved := sum - vals[s.card] + v
+			switch {
+			case v == Hero:
+				c.gateSave += pg
+				c.payout += pg * float64(saved)
+			case s.burn >= 0 && !burnsAt(above, s.burn, v):
+				c.gateSave += pg
+				factor := 1
+				used[s.card]--
+				if w := onlyValue(used); w >= 0 && vals[w] == v {
+					c.multi += pg
+					factor = n
+				}
+				used[s.card]++
+				c.payout += pg * float64(saved*factor)
+			default:
+				c.bust += pg
+			}
+		}
+	}
+	return c
+}
+
+// burnsAt() reports whether card v at index i burns against the row above it.
+func burnsAt(above []int, i, v int) bool {
+	for _, j := range []int{i - 1, i} {
+		if j >= 0 && j < len(above) && above[j] == v {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyValue() returns the index of the only value used, or -1 if none or more than one are.
+func onlyValue(used []byte) int {
+	only := -1
+	for t, n := range used {
+		if n == 0 {
+			continue
+		}
+		if only >= 0 {
+			return -1
+		}
+		only = t
+	}
+	return only
+}
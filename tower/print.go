@@ -0,0 +1,122 @@
+package tower
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func (g *Game) PrintRow(row int) {
+	w := g.deckDef.cardWidth()
+	spacing := strings.Repeat(" ", (g.rows-row)*(w+1)/2)
+	if row == 0 {
+		if len(g.tower[0]) == 0 {
+			fmt.Fprint(g.out, spacing, "[", strings.Repeat(" ", w), "]")
+		} else {
+			fmt.Fprint(g.out, spacing, "[", g.paint(strings.Repeat("?", w), ansiGate), "]")
+		}
+	} else {
+		value := fmt.Sprintf("(%d)", g.getRowValue(row))
+		if g.isJackpot() && row == g.rows-1 {
+			value = g.paint(fmt.Sprintf("(%d)", g.getJackpotValue()), ansiJackpot)
+		} else if g.isMultiRow(row) && g.colorOn() {
+			value = g.paint(fmt.Sprintf("x%d ", len(g.tower[row])), ansiMulti) + value
+		}
+		fmt.Fprint(g.out, spacing, g.formatRow(row, w), spacing, value)
+	}
+	fmt.Fprint(g.out, "\n")
+}
+
+// formatRow() prints a row like fmt prints an []int, padding each card to width w.
+// In color, each card gets its value's color, Heroes, the gate card and burned cards are highlighted,
+// and the brackets of a row that raised the multiplier are marked.
+func (g *Game) formatRow(row, w int) string {
+	burned := make(map[int]bool)
+	for _, b := range g.Burns(row) {
+		burned[b.Index] = true
+	}
+	gateRow, gateIdx, gateOk := g.GatePosition()
+
+	s := make([]string, len(g.tower[row]))
+	for i, v := range g.tower[row] {
+		code := cardColor(v)
+		switch {
+		case burned[i]:
+			code = ansiBurned
+		case gateOk && row == gateRow && i == gateIdx:
+			code = ansiGate
+		}
+		s[i] = g.paint(fmt.Sprintf("%*d", w, v), code)
+	}
+
+	left, right := "[", "]"
+	if g.isMultiRow(row) {
+		left, right = g.paint(left, ansiMulti), g.paint(right, ansiMulti)
+	}
+	return left + strings.Join(s, " ") + right
+}
+
+// isMultiRow() reports whether row raised the multiplier: every card matches and the row didn't burn.
+func (g *Game) isMultiRow(row int) bool {
+	return row > 0 && len(g.tower[row]) > 1 && allSame(g.tower[row]) && len(g.Burns(row)) == 0
+}
+
+func (g *Game) PrintTower() {
+	for row := 0; row < g.curRow; row++ {
+		g.PrintRow(row)
+	}
+	if g.IsGameOver() {
+		g.PrintRow(g.curRow)
+	}
+
+	fmt.Fprintln(g.out)
+}
+
+// Print the current game state, with instructions
+func (g *Game) PrintText() {
+	switch g.State() {
+	case StateBetting:
+		fmt.Fprintf(g.out, "Type \"z\" to bet %d, \"+\" or \"-\" to change the bet (max %d)\n", g.GetWager(), g.MaxWager())
+	case StatePlaying:
+		fmt.Fprintln(g.out, `"z" to deal the next row, "x" to cash out`)
+	case StateGameOver:
+		if g.IsBusted() {
+			fmt.Fprintln(g.out, `BUST! "z" or "x" to start a new round`)
+		} else {
+			fmt.Fprintln(g.out, `Tower complete! "z" or "x" to collect`)
+		}
+	}
+
+	fmt.Fprintf(g.out, "Money: %d\n", g.Balance())
+}
+
+// PrintOdds() prints the odds of the next deal as percentages. It prints nothing after a game over.
+func (g *Game) PrintOdds() {
+	if g.IsGameOver() {
+		return
+	}
+	o := g.Odds()
+	fmt.Fprintf(g.out, "Row %d odds: bust %.1f%%, gate save %.1f%%, multiplier %.1f%%", o.Row, 100*o.Bust, 100*o.GateSave, 100*o.Multiplier)
+	if o.Row == g.rows-1 && g.GateAvailable() {
+		fmt.Fprintf(g.out, ", jackpot %.1f%%", 100*o.Jackpot)
+	}
+	fmt.Fprintln(g.out)
+}
+
+// PrintAdvice() prints whether to hit or cash out, and why. It prints nothing if there is no choice to make.
+func (g *Game) PrintAdvice() {
+	if a, ok := g.Advise(); ok {
+		fmt.Fprint(g.out, a)
+	}
+}
+
+// IsTerminal() reports whether w is a terminal, as opposed to a file, pipe or buffer.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,108 @@
+package tower
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StartingBalance is the money a new game or profile starts with.
+const StartingBalance = 300
+
+// Profile keeps a player's balance and lifetime statistics across sessions.
+type Profile struct {
+	Name        string `json:"name"`
+	Balance     int    `json:"balance"`
+	Rounds      int    `json:"rounds"`
+	BustsByRow  []int  `json:"busts_by_row"` // index is the row that burned
+	GateSaves   int    `json:"gate_saves"`
+	Multipliers int    `json:"multipliers"` // rows that raised the multiplier
+	Jackpots    int    `json:"jackpots"`
+	Wagered     int    `json:"wagered"`
+	Won         int    `json:"won"` // total payouts, before subtracting wagers
+
+	// Streak is the current run of rounds won (positive) or lost (negative).
+	// A round that pays back exactly the wager ends both.
+	Streak            int `json:"streak"`
+	LongestWinStreak  int `json:"longest_win_streak"`
+	LongestLossStreak int `json:"longest_loss_streak"`
+}
+
+// NewProfile() creates a profile with the starting balance and no history.
+func NewProfile(name string) Profile {
+	return Profile{Name: name, Balance: StartingBalance}
+}
+
+// Add() records the result of a round. The balance is tracked separately with SetBalance(),
+// as it also changes when a round starts.
+func (p *Profile) Add(r Result) {
+	p.Rounds++
+	if r.Bust {
+		for len(p.BustsByRow) <= r.Row {
+			p.BustsByRow = append(p.BustsByRow, 0)
+		}
+		p.BustsByRow[r.Row]++
+	}
+	if r.GateSave {
+		p.GateSaves++
+	}
+	if r.Jackpot {
+		p.Jackpots++
+	}
+	p.Multipliers += r.MultiRows
+	p.Wagered += r.Wager
+	p.Won += r.Payout
+
+	switch {
+	case r.Payout > r.Wager:
+		if p.Streak < 0 {
+			p.Streak = 0
+		}
+		p.Streak++
+	case r.Payout < r.Wager:
+		if p.Streak > 0 {
+			p.Streak = 0
+		}
+		p.Streak--
+	default:
+		p.Streak = 0
+	}
+	if p.Streak > p.LongestWinStreak {
+		p.LongestWinStreak = p.Streak
+	}
+	if -p.Streak > p.LongestLossStreak {
+		p.LongestLossStreak = -p.Streak
+	}
+}
+
+// SetBalance() records the player's current balance.
+func (p *Profile) SetBalance(b int) {
+	p.Balance = b
+}
+
+// String() lays out the profile's statistics for printing.
+func (p Profile) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "Profile: %s\n", p.Name)
+	fmt.Fprintf(b, "Balance: %d\n", p.Balance)
+	fmt.Fprintf(b, "Rounds played: %d\n", p.Rounds)
+	fmt.Fprintf(b, "Total wagered: %d\n", p.Wagered)
+	fmt.Fprintf(b, "Total won: %d\n", p.Won)
+	fmt.Fprintf(b, "Net: %+d\n", p.Won-p.Wagered)
+	fmt.Fprintf(b, "Gate saves: %d\n", p.GateSaves)
+	fmt.Fprintf(b, "Multipliers hit: %d\n", p.Multipliers)
+	fmt.Fprintf(b, "Jackpots: %d\n", p.Jackpots)
+	fmt.Fprintf(b, "Longest winning streak: %d\n", p.LongestWinStreak)
+	fmt.Fprintf(b, "Longest losing streak: %d\n", p.LongestLossStreak)
+	fmt.Fprintln(b, "Busts by row:")
+	busts := false
+	for row, n := range p.BustsByRow {
+		if n > 0 {
+			fmt.Fprintf(b, "  row %d: %d\n", row, n)
+			busts = true
+		}
+	}
+	if !busts {
+		fmt.Fprintln(b, "  none")
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package tower
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrReplayMismatch is returned when the engine doesn't deal or pay a logged round the way its Record says.
+var ErrReplayMismatch = errors.New("tower: replay doesn't match the record")
+
+// Replay re-plays a logged round, one row at a time.
+//
+// The player's actions are read from the Record: bet the wager, hit until Row is dealt,
+// then cash out unless the round bust.
+type Replay struct {
+	rec   Record
+	cards []int
+	opts  []Option
+}
+
+// NewReplay() prepares rec to be replayed. The round is dealt from rec.Cards,
+// or if the record has none, from the deck shuffled with rec.RoundSeed.
+// opts are applied to every Game the replay builds, after its own deck, rows and wager.
+func NewReplay(rec Record, opts ...Option) (*Replay, error) {
+	if err := rec.Deck.Validate(); err != nil {
+		return nil, err
+	}
+	if rec.Rows < MinRows || rec.Rows > MaxRows {
+		return nil, fmt.Errorf("tower: record has %d rows, want %d to %d", rec.Rows, MinRows, MaxRows)
+	}
+	if rec.Row < 1 || rec.Row >= rec.Rows {
+		return nil, fmt.Errorf("tower: record ended on row %d of %d", rec.Row, rec.Rows)
+	}
+	if err := checkWagerStep(rec.Wager); err != nil {
+		return nil, fmt.Errorf("%w: record's wager is %d", err, rec.Wager)
+	}
+
+	cards := rec.Cards
+	if len(cards) == 0 {
+		cards = rec.Deck.shuffled(rec.RoundSeed)
+	}
+	if len(cards) < towerSize(rec.Rows) {
+		return nil, fmt.Errorf("tower: record has %d cards, a %d row tower needs %d", len(cards), rec.Rows, towerSize(rec.Rows))
+	}
+	return &Replay{rec: rec, cards: cards, opts: opts}, nil
+}
+
+// Record() returns the round being replayed.
+func (r *Replay) Record() Record {
+	return r.rec
+}
+
+// Len() returns the number of hits the player made, the last step of the replay.
+func (r *Replay) Len() int {
+	return r.rec.Row
+}
+
+// Game() returns the round after the player's first step hits, from 0 (the wager set,
+// nothing dealt) to Len(). The round is left as it was before the player cashed out.
+func (r *Replay) Game(step int) (Game, error) {
+	if step < 0 || step > r.Len() {
+		return Game{}, fmt.Errorf("tower: step %d of a %d step replay", step, r.Len())
+	}
+	return r.game(step)
+}
+
+// game() builds the round and hits step times, with extra options after the replay's own.
+func (r *Replay) game(step int, extra ...Option) (Game, error) {
+	opts := []Option{
+		WithDeck(r.rec.Deck),
+		WithRows(r.rec.Rows),
+		WithMaxWager(max(r.rec.Wager, DefaultMaxWager)),
+		WithBalance(r.rec.Wager),
+	}
+	if r.rec.Seed != 0 {
+		opts = append(opts, WithSeed(r.rec.Seed))
+	}
+	g, err := NewGame(append(append(opts, r.opts...), extra...)...)
+	if err != nil {
+		return Game{}, err
+	}
+	g.setDeck(append([]int{}, r.cards...))
+	g.roundSeed = r.rec.RoundSeed
+	if err := g.SetWager(r.rec.Wager); err != nil {
+		return Game{}, err
+	}
+
+	for i := 0; i < step; i++ {
+		if g.IsGameOver() {
+			return g, fmt.Errorf("%w: round ended on row %d, the record hits to row %d", ErrReplayMismatch, g.CurRow(), r.rec.Row)
+		}
+		if err := g.Hit(); err != nil {
+			return g, err
+		}
+	}
+	return g, nil
+}
+
+// Verify() plays the whole round and checks the engine deals, burns, multiplies and pays it
+// exactly as recorded. A difference is reported as ErrReplayMismatch.
+func (r *Replay) Verify() error {
+	var got *Record
+	g, err := r.game(r.Len(), WithRoundLog(func(rec Record) { got = &rec }))
+	if err != nil {
+		return err
+	}
+	if !g.IsBusted() {
+		g.CashOut()
+	}
+	if got == nil {
+		return fmt.Errorf("%w: round didn't end", ErrReplayMismatch)
+	}
+
+	want := r.rec
+	checks := []struct {
+		field     string
+		want, got any
+	}{
+		{"dealt", want.Dealt, got.Dealt},
+		{"gate", gateString(want.Gate), gateString(got.Gate)},
+		{"burns", want.Burns, got.Burns},
+		{"multiplier rows", want.MultiplierRows, got.MultiplierRows},
+		{"result", want.Result, got.Result},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.want, c.got) {
+			return fmt.Errorf("%w: %s recorded as %+v, engine gives %+v", ErrReplayMismatch, c.field, c.want, c.got)
+		}
+	}
+	return nil
+}
+
+// gateString() describes where the gate card was played, for comparing and printing.
+func gateString(g *GateReveal) string {
+	if g == nil {
+		return "face down"
+	}
+	return fmt.Sprintf("%d on row %d, card %d", g.Card, g.Row, g.Index)
+}
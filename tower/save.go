@@ -0,0 +1,189 @@
+package tower
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+)
+
+// SaveVersion is the version of the save format written by Save().
+// Load() rejects saves written with any other version.
+const SaveVersion = 1
+
+var (
+	ErrSaveVersion = errors.New("tower: save was written by a different version")
+	ErrCorruptSave = errors.New("tower: corrupt save")
+	ErrUnsaveable  = errors.New("tower: games using WithSource() can't be saved")
+)
+
+// save is the JSON form of a Game. The RNG is stored as the session seed and the number of
+// rounds shuffled from it, so loading replays the seed to the same point.
+type save struct {
+	Version    int     `json:"version"`
+	Seed       int64   `json:"seed"`
+	Rounds     int     `json:"rounds"`
+	RoundSeed  int64   `json:"round_seed"`
+	Deck       Deck    `json:"deck"`
+	Rows       int     `json:"rows"`
+	Balance    int     `json:"balance"`
+	Wager      int     `json:"wager"`
+	MaxWager   int     `json:"max_wager"`
+	State      int     `json:"state"`
+	Bust       bool    `json:"bust"`
+	CurRow     int     `json:"cur_row"`
+	Multiplier int     `json:"multiplier"`
+	GateRow    int     `json:"gate_row"`
+	GateIdx    int     `json:"gate_idx"`
+	Cards      []int   `json:"cards"` // the rest of the deck, in the order it will be dealt
+	Tower      [][]int `json:"tower"`
+
+	// The round so far, so the round log of a resumed round is complete.
+	RoundCards     []int      `json:"round_cards,omitempty"`
+	Dealt          [][]int    `json:"dealt,omitempty"`
+	Burns          []RowBurns `json:"burns,omitempty"`
+	MultiplierRows []int      `json:"multiplier_rows,omitempty"`
+}
+
+// Save() writes the game, mid-round or not, as JSON.
+func (g *Game) Save(w io.Writer) error {
+	if !g.seeded {
+		return ErrUnsaveable
+	}
+	s := save{
+		Version:    SaveVersion,
+		Seed:       g.seed,
+		Rounds:     g.rounds,
+		RoundSeed:  g.roundSeed,
+		Deck:       g.deckDef,
+		Rows:       g.rows,
+		Balance:    g.balance,
+		Wager:      g.wager,
+		MaxWager:   g.maxWager,
+		State:      g.state,
+		Bust:       g.bust,
+		CurRow:     g.curRow,
+		Multiplier: g.multiplier,
+		GateRow:    g.gateRow,
+		GateIdx:    g.gateIdx,
+		Cards:      g.deck,
+		Tower:      g.tower,
+
+		RoundCards:     g.history.cards,
+		Dealt:          g.history.dealt,
+		Burns:          g.history.burns,
+		MultiplierRows: g.history.multiRows,
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// Load() reads a game written by Save(). The game continues exactly where it was saved,
+// including the shuffles of later rounds. opts can set anything that isn't saved,
+// such as the output, colors and round end hooks.
+func Load(r io.Reader, opts ...Option) (Game, error) {
+	var s save
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return Game{}, fmt.Errorf("%w: %v", ErrCorruptSave, err)
+	}
+	if s.Version != SaveVersion {
+		return Game{}, fmt.Errorf("%w: save is version %d, want %d", ErrSaveVersion, s.Version, SaveVersion)
+	}
+	if err := s.check(); err != nil {
+		return Game{}, fmt.Errorf("%w: %v", ErrCorruptSave, err)
+	}
+
+	opts = append([]Option{WithDeck(s.Deck), WithRows(s.Rows), WithMaxWager(s.MaxWager)}, opts...)
+	g, err := NewGame(opts...)
+	if err != nil {
+		return Game{}, fmt.Errorf("%w: %v", ErrCorruptSave, err)
+	}
+
+	g.seed, g.seeded = s.Seed, true
+	g.src = rand.NewSource(s.Seed)
+	for i := 0; i < s.Rounds; i++ {
+		g.src.Int63()
+	}
+	g.rounds, g.roundSeed = s.Rounds, s.RoundSeed
+
+	g.balance, g.wager = s.Balance, s.Wager
+	g.state, g.bust = s.State, s.Bust
+	g.curRow, g.multiplier = s.CurRow, s.Multiplier
+	g.gateRow, g.gateIdx = s.GateRow, s.GateIdx
+	g.tower = s.Tower
+	g.history = history{cards: s.RoundCards, dealt: s.Dealt, burns: s.Burns, multiRows: s.MultiplierRows}
+	// every card dealt so far has been counted off, so the counts are whatever is left in the deck
+	g.setDeck(s.Cards)
+	return g, nil
+}
+
+// check() makes sure a save describes a game that can be played on without panicking.
+func (s save) check() error {
+	if err := s.Deck.Validate(); err != nil {
+		return err
+	}
+	if s.Rows < MinRows || s.Rows > MaxRows {
+		return fmt.Errorf("%d rows", s.Rows)
+	}
+	if len(s.Tower) != s.Rows {
+		return fmt.Errorf("tower has %d rows, want %d", len(s.Tower), s.Rows)
+	}
+	if s.CurRow < 0 || s.CurRow >= s.Rows {
+		return fmt.Errorf("current row %d", s.CurRow)
+	}
+	if s.State < StateBetting || s.State > StateGameOver {
+		return fmt.Errorf("state %d", s.State)
+	}
+	if s.Multiplier < 1 {
+		return fmt.Errorf("multiplier %d", s.Multiplier)
+	}
+	if s.Wager <= 0 || s.Wager%WagerStep != 0 || s.Wager > s.MaxWager {
+		return fmt.Errorf("wager %d", s.Wager)
+	}
+	if s.Rounds < 0 {
+		return fmt.Errorf("%d rounds", s.Rounds)
+	}
+
+	if s.State != StateBetting && s.CurRow < 1 {
+		return fmt.Errorf("state %d on row %d", s.State, s.CurRow)
+	}
+	if s.Bust && s.State != StateGameOver {
+		return fmt.Errorf("bust in state %d", s.State)
+	}
+	if s.GateRow < 0 || s.GateRow > s.CurRow || s.GateIdx < 0 || s.GateIdx > s.GateRow {
+		return fmt.Errorf("gate card played on row %d at %d", s.GateRow, s.GateIdx)
+	}
+
+	values := s.Deck.counts()
+	for i, row := range s.Tower {
+		// rows are dealt in order: every row before the current one is full and every row after it
+		// is empty. The current row is full once the round is over. The gate row empties once
+		// the gate card is played.
+		want := 0
+		if i < s.CurRow || i == s.CurRow && s.State == StateGameOver {
+			want = i + 1
+		}
+		if i == 0 && s.GateRow > 0 {
+			want = 0
+		}
+		if len(row) != want {
+			return fmt.Errorf("row %d has %d cards, want %d on row %d in state %d", i, len(row), want, s.CurRow, s.State)
+		}
+		for _, v := range row {
+			if _, ok := values[v]; !ok {
+				return fmt.Errorf("card %d is not in the deck", v)
+			}
+		}
+	}
+	for _, v := range s.Cards {
+		if _, ok := values[v]; !ok {
+			return fmt.Errorf("card %d is not in the deck", v)
+		}
+	}
+	if need := towerSize(s.Rows) - towerSize(s.CurRow); len(s.Cards) < need {
+		return fmt.Errorf("%d cards left, the tower needs %d more", len(s.Cards), need)
+	}
+	return nil
+}
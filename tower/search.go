@@ -0,0 +1,250 @@
+package tower
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// searchLimit is how much work a search may do for one choice before it stops dealing out more
+// rows ahead, counting every row it deals out and the work rowOdds() does. Every way the rest of
+// a full height tower could be dealt is far too much to go through, so near the top of one the
+// search only sees a row or two ahead.
+const searchLimit = 100000
+
+// search works out what hitting is worth from a position in a round, playing the best way from
+// there: after every row, whichever of cashing out and hitting on is worth more. Hitting is worth
+// every way the next row could be dealt from the unseen cards, each played on the best way in turn,
+// and the last row is worth what rowOdds() works out for it. A position is worth the same however
+// it was reached, so each is only valued once. Worth is per unit of the multiplier, which only
+// scales it.
+//
+// The search deals out one more row ahead at a time. Once the next row ahead would take more work
+// than its limit, it stops, and the positions it didn't deal out from are valued by cashing out or
+// hitting once more, whichever is worth more. Hitting is then worth at least what it found.
+type search struct {
+	vals  []int // the deck's card values, ascending, so the Hero comes first
+	rows  int
+	limit int
+
+	work int // done so far, towards limit
+	memo map[string]float64
+}
+
+// position is a point in a round where there's a choice to make. above is the row last dealt,
+// and unseen counts the cards not seen yet by the index of their value, the gate card included
+// while it's face down. dealt is what the rows dealt so far add up to, for the jackpot.
+// It follows from unseen, so it isn't part of the memo key.
+type position struct {
+	row    int
+	above  []int
+	unseen []int
+	gate   bool
+	dealt  int
+}
+
+// newSearch() returns a search of rounds dealt from deck to a tower of rows, that does at most
+// limit work for each choice.
+func newSearch(deck Deck, rows, limit int) *search {
+	s := &search{rows: rows, limit: limit}
+	for v := range deck.counts() {
+		s.vals = append(s.vals, v)
+	}
+	sort.Ints(s.vals)
+	return s
+}
+
+// position() returns where g is in its round. g must be playing.
+func (s *search) position(g *Game) position {
+	p := position{row: g.lastDealtRow(), gate: g.GateAvailable(), unseen: make([]int, len(s.vals)), dealt: g.getJackpotValue()}
+	p.above = append([]int{}, g.tower[p.row]...)
+	for t, v := range s.vals {
+		p.unseen[t] = g.counts[v]
+		if p.gate && g.tower[0][0] == v {
+			p.unseen[t]++
+		}
+	}
+	return p
+}
+
+// hit() returns what hitting is worth at p, and whether that's exact: whether the search dealt
+// out every way the rest of the tower could come. Dealing out more rows only ever finds hitting
+// worth more, so the search stops once it's worth more than enough.
+func (s *search) hit(p position, enough float64) (worth float64, exact bool) {
+	last := s.rows - 2 - p.row // the rows to deal out before the last one
+	for depth := 0; depth <= last && (depth == 0 || worth <= enough); depth++ {
+		s.work, s.memo = 0, make(map[string]float64)
+		w, ok := s.hitFrom(p, depth)
+		if !ok {
+			break
+		}
+		worth, exact = w, depth == last
+	}
+	return worth, exact
+}
+
+// hitFrom() returns what hitting is worth at p, dealing out depth rows ahead. It returns false
+// if that would take more work than the limit.
+func (s *search) hitFrom(p position, depth int) (float64, bool) {
+	n := p.row + 1
+	if n == s.rows-1 || depth == 0 {
+		jackpot := -1
+		if n == s.rows-1 && p.gate {
+			jackpot = p.dealt
+		}
+		c := rowOdds(p.above, s.counts(p.unseen), n+1, p.gate, jackpot)
+		s.work += c.work
+		return c.payout, true
+	}
+
+	worth := 0.0
+	s.deal(p, func(next position, prob float64, factor int) {
+		if v, ok := s.value(next, depth-1); ok {
+			worth += prob * float64(factor) * v
+		}
+	})
+	return worth, s.work <= s.limit
+}
+
+// value() returns what p is worth, the most of cashing out and hitting.
+func (s *search) value(p position, depth int) (float64, bool) {
+	key := s.key(p, depth)
+	if v, ok := s.memo[key]; ok {
+		return v, true
+	}
+	hit, ok := s.hitFrom(p, depth)
+	if !ok {
+		return 0, false
+	}
+	v := math.Max(float64(sum(p.above)), hit)
+	s.memo[key] = v
+	return v, true
+}
+
+// deal() calls f with every way the next row could be dealt at p and not bust, once the gate card
+// has been played if it has to be, with its chance and the multiplier it raises, 1 if none.
+// The row is dealt one card at a time, and given up on once nothing left could save it.
+// Dealing stops early once the search has done more work than its limit.
+func (s *search) deal(p position, f func(next position, prob float64, factor int)) {
+	unseen := append([]int{}, p.unseen...)
+	left := 0
+	for _, c := range unseen {
+		left += c
+	}
+	row := make([]int, p.row+2)
+	burned := []int{} // the indexes of the cards that burn, in order
+
+	var next func(i int, prob float64, hero bool)
+	next = func(i int, prob float64, hero bool) {
+		if s.work > s.limit {
+			return
+		}
+		if !hero && s.vals[0] == Hero && unseen[0] == 0 {
+			// nothing can protect the row, and only the gate card can save one burned card
+			if len(burned) > 1 || len(burned) == 1 && !p.gate {
+				return
+			}
+		}
+		if i == len(row) {
+			s.settle(p, row, unseen, burned, hero, prob, f)
+			return
+		}
+		for t, v := range s.vals {
+			if unseen[t] == 0 {
+				continue
+			}
+			q := prob * float64(unseen[t]) / float64(left)
+			unseen[t]--
+			left--
+			row[i] = v
+			burns := burnsAt(p.above, i, v)
+			if burns {
+				burned = append(burned, i)
+			}
+			next(i+1, q, hero || v == Hero)
+			if burns {
+				burned = burned[:len(burned)-1]
+			}
+			unseen[t]++
+			left++
+		}
+	}
+	next(0, 1, false)
+}
+
+// settle() calls f with how a dealt row turns out, playing the gate card if the row burns:
+// it's one of the cards left, and it replaces the first card that burned.
+func (s *search) settle(p position, row, unseen, burned []int, hero bool, prob float64, f func(position, float64, int)) {
+	s.work++
+	if hero || len(burned) == 0 {
+		next := position{row: p.row + 1, above: append([]int{}, row...), unseen: append([]int{}, unseen...), gate: p.gate, dealt: p.dealt + sum(row)}
+		f(next, prob, rowFactor(row))
+		return
+	}
+	if !p.gate {
+		return
+	}
+	left := 0
+	for _, c := range unseen {
+		left += c
+	}
+	for t, v := range s.vals {
+		if unseen[t] == 0 {
+			continue
+		}
+		// a Hero protects the whole row, any other card saves it only if nothing else burned
+		if v != Hero && (len(burned) > 1 || burnsAt(p.above, burned[0], v)) {
+			continue
+		}
+		next := position{row: p.row + 1, above: append([]int{}, row...), unseen: append([]int{}, unseen...)}
+		next.above[burned[0]] = v
+		next.dealt = p.dealt + sum(next.above)
+		next.unseen[t]--
+		f(next, prob*float64(unseen[t])/float64(left), rowFactor(next.above))
+	}
+}
+
+// key() returns the memo key for p, dealing out depth rows ahead.
+func (s *search) key(p position, depth int) string {
+	b := make([]byte, 0, 2*(len(p.above)+len(p.unseen))+4)
+	b = binary.AppendUvarint(b, uint64(p.row))
+	b = binary.AppendUvarint(b, uint64(depth))
+	if p.gate {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	for _, v := range p.above {
+		b = binary.AppendUvarint(b, uint64(v))
+	}
+	for _, c := range p.unseen {
+		b = binary.AppendUvarint(b, uint64(c))
+	}
+	return string(b)
+}
+
+// counts() returns unseen by card value, as rowOdds() takes it.
+func (s *search) counts(unseen []int) map[int]int {
+	c := make(map[int]int, len(unseen))
+	for t, n := range unseen {
+		c[s.vals[t]] = n
+	}
+	return c
+}
+
+// rowFactor() returns what a row multiplies the multiplier by: its length if every card matches, else 1.
+func rowFactor(row []int) int {
+	if len(row) > 1 && allSame(row) {
+		return len(row)
+	}
+	return 1
+}
+
+// sum() returns what cards add up to.
+func sum(cards []int) int {
+	total := 0
+	for _, v := range cards {
+		total += v
+	}
+	return total
+}
@@ -0,0 +1,285 @@
+package tower
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// simChunk is how many rounds a simulation plays from each seed. The chunks, not the workers,
+// decide the shuffles, so a simulation comes out the same on any number of workers.
+const simChunk = 1000
+
+// z95 is the number of standard errors either side of an estimate in a 95% confidence interval.
+const z95 = 1.96
+
+// SimConfig configures Simulate().
+type SimConfig struct {
+	Deck    Deck
+	Rows    int
+	Wager   int
+	Rounds  int
+	Seed    int64
+	Workers int // goroutines to play on, at least 1
+
+	// Strategy plays the rounds. It's called from every worker at once.
+	// Counts lets it see the unseen cards.
+	Strategy Strategy
+	Counts   bool
+}
+
+// Simulation is what happened over the rounds of Simulate().
+type Simulation struct {
+	Rounds      int
+	Wagered     int64
+	Paid        int64
+	Payouts     map[int]int // rounds by payout per WagerStep of the wager, 0 for every bust
+	Reached     []int       // rounds that dealt each row
+	BustsByRow  []int       // rounds that bust on each row
+	GateUsed    int         // rounds the gate card was played in, saving the row or not
+	GateSaves   int
+	MultiRounds int // rounds with at least one row that raised the multiplier
+	MultiRows   int // rows that raised the multiplier, over every round
+	Jackpots    int
+
+	// sums for the return to player's confidence interval
+	sumPaidSq, sumWagerSq, sumPaidWager float64
+}
+
+// Simulate() plays cfg.Rounds rounds headless, each from the same starting balance, by
+// cfg.Strategy. Rounds are dealt in chunks shuffled from seeds worked out from cfg.Seed,
+// so the results only depend on the seed, not on the number of workers.
+func Simulate(cfg SimConfig) (Simulation, error) {
+	if cfg.Rounds < 1 {
+		return Simulation{}, errors.New("tower: simulating needs at least 1 round")
+	}
+	if cfg.Strategy == nil {
+		return Simulation{}, errors.New("tower: simulating needs a strategy")
+	}
+	// check the config once, before starting any workers
+	if _, err := newSimGame(cfg, cfg.Seed); err != nil {
+		return Simulation{}, err
+	}
+
+	chunks := (cfg.Rounds + simChunk - 1) / simChunk
+	results := make([]Simulation, chunks)
+	errs := make([]error, chunks)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(cfg.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range next {
+				rounds := min(simChunk, cfg.Rounds-c*simChunk)
+				results[c], errs[c] = simulateChunk(cfg, ChunkSeed(cfg.Seed, c), rounds)
+			}
+		}()
+	}
+	for c := 0; c < chunks; c++ {
+		next <- c
+	}
+	close(next)
+	wg.Wait()
+
+	sim := newSimulation(cfg.Rows)
+	for c, r := range results {
+		if errs[c] != nil {
+			return Simulation{}, errs[c]
+		}
+		sim.add(r)
+	}
+	return sim, nil
+}
+
+// ChunkSeed() returns the seed the chunk'th run of rounds of a simulation seeded with seed
+// is shuffled from. Anything dealing the same chunk deals the same cards.
+func ChunkSeed(seed int64, chunk int) int64 {
+	// splitmix64, so neighbouring chunks get unrelated seeds
+	z := uint64(seed) + uint64(chunk+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+// newSimGame() creates the game a chunk of a simulation is played on.
+func newSimGame(cfg SimConfig, seed int64) (Game, error) {
+	g, err := NewGame(WithDeck(cfg.Deck), WithRows(cfg.Rows), WithMaxWager(max(cfg.Wager, DefaultMaxWager)), WithSeed(seed))
+	if err != nil {
+		return Game{}, err
+	}
+	if err := g.SetWager(cfg.Wager); err != nil {
+		return Game{}, err
+	}
+	g.out = io.Discard
+	return g, nil
+}
+
+// simulateChunk() plays rounds on a game shuffled from seed. Every round starts with enough
+// to bet the table maximum.
+func simulateChunk(cfg SimConfig, seed int64, rounds int) (Simulation, error) {
+	sim := newSimulation(cfg.Rows)
+	g, _ := newSimGame(cfg, seed)
+	g.onRoundEnd = []func(Result){func(r Result) { sim.record(r, !g.GateAvailable()) }}
+	rng := StrategyRand(seed)
+
+	for i := 0; i < rounds; i++ {
+		g.balance = max(StartingBalance, g.maxWager)
+		if err := g.PlayRound(cfg.Strategy, rng, cfg.Counts); err != nil {
+			return Simulation{}, err
+		}
+	}
+	return sim, nil
+}
+
+func newSimulation(rows int) Simulation {
+	return Simulation{Payouts: make(map[int]int), Reached: make([]int, rows), BustsByRow: make([]int, rows)}
+}
+
+// record() adds a round to the simulation.
+func (s *Simulation) record(r Result, gateUsed bool) {
+	s.Rounds++
+	s.Wagered += int64(r.Wager)
+	s.Paid += int64(r.Payout)
+	s.Payouts[r.Payout*WagerStep/r.Wager]++
+	for row := 1; row <= r.Row; row++ {
+		s.Reached[row]++
+	}
+	if r.Bust {
+		s.BustsByRow[r.Row]++
+	}
+	if gateUsed {
+		s.GateUsed++
+	}
+	if r.GateSave {
+		s.GateSaves++
+	}
+	if r.MultiRows > 0 {
+		s.MultiRounds++
+	}
+	s.MultiRows += r.MultiRows
+	if r.Jackpot {
+		s.Jackpots++
+	}
+
+	p, w := float64(r.Payout), float64(r.Wager)
+	s.sumPaidSq += p * p
+	s.sumWagerSq += w * w
+	s.sumPaidWager += p * w
+}
+
+// add() adds the rounds of o to the simulation.
+func (s *Simulation) add(o Simulation) {
+	s.Rounds += o.Rounds
+	s.Wagered += o.Wagered
+	s.Paid += o.Paid
+	for p, n := range o.Payouts {
+		s.Payouts[p] += n
+	}
+	for row := range s.Reached {
+		s.Reached[row] += o.Reached[row]
+		s.BustsByRow[row] += o.BustsByRow[row]
+	}
+	s.GateUsed += o.GateUsed
+	s.GateSaves += o.GateSaves
+	s.MultiRounds += o.MultiRounds
+	s.MultiRows += o.MultiRows
+	s.Jackpots += o.Jackpots
+	s.sumPaidSq += o.sumPaidSq
+	s.sumWagerSq += o.sumWagerSq
+	s.sumPaidWager += o.sumPaidWager
+}
+
+// RTP() returns the return to player, payouts over wagers, and the half width of its 95% confidence interval.
+func (s Simulation) RTP() (rtp, ci float64) {
+	if s.Wagered == 0 {
+		return 0, 0
+	}
+	n := float64(s.Rounds)
+	rtp = float64(s.Paid) / float64(s.Wagered)
+	if s.Rounds < 2 {
+		return rtp, 0
+	}
+	// RTP is a ratio of two sums, so its variance comes from the spread of payout - rtp*wager
+	spread := (s.sumPaidSq - 2*rtp*s.sumPaidWager + rtp*rtp*s.sumWagerSq) / (n - 1)
+	meanWager := float64(s.Wagered) / n
+	return rtp, z95 * math.Sqrt(math.Max(spread, 0)/n) / meanWager
+}
+
+// Rate() returns k out of n as a share, and the half width of its 95% confidence interval.
+func Rate(k, n int) (rate, ci float64) {
+	if n == 0 {
+		return 0, 0
+	}
+	rate = float64(k) / float64(n)
+	return rate, z95 * math.Sqrt(rate*(1-rate)/float64(n))
+}
+
+// payoutBands are the payouts WriteReport() groups rounds by, as multiples of the wager.
+// The first band is rounds that paid nothing.
+var payoutBands = []struct {
+	name string
+	upTo float64 // payouts below upTo times the wager, and above the band before
+}{
+	{"nothing", 0},
+	{"under 1x", 1},
+	{"1x to 2x", 2},
+	{"2x to 5x", 5},
+	{"5x to 10x", 10},
+	{"10x to 50x", 50},
+	{"50x and up", math.Inf(1)},
+}
+
+// WriteReport() writes the simulation's results.
+func (s Simulation) WriteReport(w io.Writer) error {
+	b := &strings.Builder{}
+	rtp, ci := s.RTP()
+	fmt.Fprintf(b, "Rounds: %d, wagered %d, paid %d\n", s.Rounds, s.Wagered, s.Paid)
+	fmt.Fprintf(b, "Return to player: %.2f%% ± %.2f%%\n", 100*rtp, 100*ci)
+	for _, line := range []struct {
+		name string
+		k    int
+	}{
+		{"Jackpots", s.Jackpots},
+		{"Gate card played", s.GateUsed},
+		{"Saved by the gate", s.GateSaves},
+		{"Multiplier raised", s.MultiRounds},
+	} {
+		rate, ci := Rate(line.k, s.Rounds)
+		fmt.Fprintf(b, "%s: %.2f%% ± %.2f%% of rounds\n", line.name, 100*rate, 100*ci)
+	}
+	fmt.Fprintf(b, "Rows raising the multiplier: %d\n", s.MultiRows)
+
+	fmt.Fprintf(b, "\n%4s  %9s  %9s  %17s\n", "Row", "Dealt", "Busts", "Bust rate")
+	for row := 1; row < len(s.Reached); row++ {
+		rate, ci := Rate(s.BustsByRow[row], s.Reached[row])
+		fmt.Fprintf(b, "%4d  %9d  %9d  %7.2f%% ± %5.2f%%\n", row, s.Reached[row], s.BustsByRow[row], 100*rate, 100*ci)
+	}
+
+	bands := make([]int, len(payoutBands))
+	payouts := []int{}
+	for p := range s.Payouts {
+		payouts = append(payouts, p)
+	}
+	sort.Ints(payouts)
+	for _, p := range payouts {
+		for i, band := range payoutBands {
+			if (p == 0 && i == 0) || (p > 0 && i > 0 && float64(p) < band.upTo*WagerStep) {
+				bands[i] += s.Payouts[p]
+				break
+			}
+		}
+	}
+	fmt.Fprintf(b, "\n%-12s  %9s  %17s\n", "Payout", "Rounds", "Share")
+	for i, band := range payoutBands {
+		rate, ci := Rate(bands[i], s.Rounds)
+		fmt.Fprintf(b, "%-12s  %9d  %7.2f%% ± %5.2f%%\n", band.name, bands[i], 100*rate, 100*ci)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,312 @@
+package tower
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// ActionKind is what a Strategy decides to do.
+type ActionKind int
+
+const (
+	ActionBet     ActionKind = iota // start a round, betting Action.Wager
+	ActionHit                       // deal the next row
+	ActionCashOut                   // collect the last row
+)
+
+var actionNames = []string{"bet", "hit", "cash_out"}
+
+func (k ActionKind) String() string {
+	if k < 0 || int(k) >= len(actionNames) {
+		return fmt.Sprintf("ActionKind(%d)", int(k))
+	}
+	return actionNames[k]
+}
+
+// MarshalText() writes the action as its name, for JSON.
+func (k ActionKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(actionNames) {
+		return nil, fmt.Errorf("tower: unknown action %d", int(k))
+	}
+	return []byte(actionNames[k]), nil
+}
+
+// UnmarshalText() reads an action's name.
+func (k *ActionKind) UnmarshalText(b []byte) error {
+	for i, name := range actionNames {
+		if string(b) == name {
+			*k = ActionKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("tower: unknown action %q, want bet, hit or cash_out", b)
+}
+
+// Action is a Strategy's decision. Before a round it must be ActionBet;
+// during one, ActionHit or ActionCashOut.
+type Action struct {
+	Kind  ActionKind `json:"action"`
+	Wager int        `json:"wager,omitempty"` // for ActionBet, 0 bets the current wager
+}
+
+func (a Action) String() string {
+	switch a.Kind {
+	case ActionBet:
+		if a.Wager == 0 {
+			return "bet"
+		}
+		return fmt.Sprintf("bet %d", a.Wager)
+	case ActionCashOut:
+		return "cash out"
+	}
+	return a.Kind.String()
+}
+
+// View is what a player can see of a game.
+type View struct {
+	State      int     `json:"state"`
+	Deck       Deck    `json:"deck"`
+	Rows       int     `json:"rows"`
+	Tower      [][]int `json:"tower"` // the rows dealt so far, with the gate row empty: the gate card is face down until it's played
+	Row        int     `json:"row"`   // the last row dealt, 0 before the round starts
+	GateUsed   bool    `json:"gate_used"`
+	GateRow    int     `json:"gate_row"` // the row the gate card was played into, 0 while it's face down
+	Multiplier int     `json:"multiplier"`
+	Balance    int     `json:"balance"`
+	Wager      int     `json:"wager"`
+	MaxWager   int     `json:"max_wager"`
+	CashOut    int     `json:"cash_out"` // what cashing out pays now
+
+	// Counts are the cards the player hasn't seen by value: the rest of the deck and the
+	// face down gate card. They're only filled in if the table allows counting cards.
+	Counts map[int]int `json:"counts,omitempty"`
+}
+
+// View() returns what the player can see of g, with the unseen cards if counts is true.
+func (g *Game) View(counts bool) View {
+	gateRow, _, gateUsed := g.GatePosition()
+	v := View{
+		State:      g.state,
+		Deck:       g.deckDef,
+		Rows:       g.rows,
+		Tower:      [][]int{},
+		GateUsed:   gateUsed,
+		GateRow:    gateRow,
+		Multiplier: g.multiplier,
+		Balance:    g.balance,
+		Wager:      g.wager,
+		MaxWager:   g.maxWager,
+		CashOut:    g.cashOutValue(),
+	}
+	if row := g.lastDealtRow(); g.state != StateBetting && row >= 0 {
+		v.Row = row
+		for r := 0; r <= v.Row; r++ {
+			v.Tower = append(v.Tower, append([]int{}, g.tower[r]...))
+		}
+		v.Tower[0] = []int{} // the gate card is never seen in the gate row
+	}
+	if counts {
+		v.Counts = v.unseen()
+	}
+	return v
+}
+
+// unseen() works out the cards not seen yet from the deck and the visible tower.
+func (v View) unseen() map[int]int {
+	c := v.Deck.counts()
+	for _, row := range v.Tower {
+		for _, card := range row {
+			c[card]--
+		}
+	}
+	return c
+}
+
+// game() rebuilds a game from the view, for strategies that look ahead. The gate card, if it's
+// face down, and the deck are made up from the unseen cards, so only what the view shows is known.
+func (v View) game() Game {
+	g := Game{
+		deckDef:    v.Deck,
+		rows:       v.Rows,
+		state:      v.State,
+		multiplier: v.Multiplier,
+		balance:    v.Balance,
+		wager:      v.Wager,
+		maxWager:   v.MaxWager,
+		curRow:     v.Row + 1,
+		out:        io.Discard,
+		tower:      make([][]int, v.Rows),
+	}
+	for r, row := range v.Tower {
+		g.tower[r] = append([]int{}, row...)
+	}
+	unseen := v.unseen()
+	cards := []int{}
+	for _, val := range append([]int{Hero}, v.Deck.Values...) {
+		for i := 0; i < unseen[val]; i++ {
+			cards = append(cards, val)
+		}
+	}
+	if !v.GateUsed && len(cards) > 0 {
+		g.tower[0] = []int{cards[0]}
+		cards = cards[1:]
+	}
+	g.setDeck(cards)
+	return g
+}
+
+// Strategy plays the game: it's asked what to do before every round, and after every row.
+// rng is for strategies that decide at random, so the caller can make them repeatable.
+// An error means the strategy couldn't decide, like a bot that stopped answering.
+// Simulate() calls a Strategy from several goroutines at once.
+type Strategy interface {
+	Decide(v View, rng *rand.Rand) (Action, error)
+}
+
+// StrategyFunc lets a func be a Strategy.
+type StrategyFunc func(v View, rng *rand.Rand) (Action, error)
+
+func (f StrategyFunc) Decide(v View, rng *rand.Rand) (Action, error) {
+	return f(v, rng)
+}
+
+// hitOrCashOut() decides a round with hit, betting the current wager before it.
+func hitOrCashOut(hit func(v View, rng *rand.Rand) bool) Strategy {
+	return StrategyFunc(func(v View, rng *rand.Rand) (Action, error) {
+		switch {
+		case v.State == StateBetting:
+			return Action{Kind: ActionBet, Wager: v.Wager}, nil
+		case hit(v, rng):
+			return Action{Kind: ActionHit}, nil
+		}
+		return Action{Kind: ActionCashOut}, nil
+	})
+}
+
+// HitTo() keeps hitting until row is dealt.
+func HitTo(row int) Strategy {
+	return hitOrCashOut(func(v View, _ *rand.Rand) bool {
+		return v.Row < row
+	})
+}
+
+// CashOutAt() keeps hitting until cashing out would pay at least gold.
+func CashOutAt(gold int) Strategy {
+	return hitOrCashOut(func(v View, _ *rand.Rand) bool {
+		return v.CashOut < gold
+	})
+}
+
+// Random() hits or cashes out at random, hitting with chance p.
+func Random(p float64) Strategy {
+	return hitOrCashOut(func(_ View, rng *rand.Rand) bool {
+		return rng.Float64() < p
+	})
+}
+
+// EV() follows Advise(), hitting whenever hitting is expected to pay more than cashing out.
+// Where the advice can't tell, it cashes out.
+func EV() Strategy {
+	return hitOrCashOut(func(v View, _ *rand.Rand) bool {
+		g := v.game()
+		hit, _ := g.advisedHit()
+		return hit
+	})
+}
+
+// StrategyNames lists the strategies NewStrategy() knows, as they're written.
+var StrategyNames = []string{"hit-to:<row>", "cash-out-at:<gold>", "ev", "random", "random:<chance to hit>"}
+
+// NewStrategy() returns the built-in strategy called name.
+func NewStrategy(name string) (Strategy, error) {
+	kind, arg, hasArg := strings.Cut(name, ":")
+	bad := fmt.Errorf("tower: unknown strategy %q, want one of %s", name, strings.Join(StrategyNames, ", "))
+
+	switch kind {
+	case "hit-to", "cash-out-at":
+		n, err := strconv.Atoi(arg)
+		if !hasArg || err != nil || n < 1 {
+			return nil, bad
+		}
+		if kind == "hit-to" {
+			return HitTo(n), nil
+		}
+		return CashOutAt(n), nil
+	case "random":
+		p := 0.5
+		if hasArg {
+			var err error
+			if p, err = strconv.ParseFloat(arg, 64); err != nil || p < 0 || p > 1 {
+				return nil, bad
+			}
+		}
+		return Random(p), nil
+	case "ev":
+		if hasArg {
+			return nil, bad
+		}
+		return EV(), nil
+	}
+	return nil, bad
+}
+
+// StrategyRand() returns the source of randomness for a strategy playing rounds shuffled from seed.
+// It's worked out from seed, but doesn't follow the shuffles.
+func StrategyRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(ChunkSeed(seed, -1)))
+}
+
+// ErrBadAction is returned when a Strategy decides something the game doesn't allow.
+var ErrBadAction = errors.New("tower: strategy made a move that isn't allowed")
+
+// Step() asks s what to do and does it. Before a round it bets s's wager and deals the first rows,
+// during one it hits or cashes out. counts lets s see the unseen cards, rng is passed on to s.
+// After a game over there is nothing to decide, and Step() collects without asking.
+// If s can't decide, or decides something that isn't allowed, the game is left as it was.
+func (g *Game) Step(s Strategy, rng *rand.Rand, counts bool) (Action, error) {
+	if g.IsGameOver() {
+		g.CashOut()
+		return Action{Kind: ActionCashOut}, nil
+	}
+
+	a, err := s.Decide(g.View(counts), rng)
+	if err != nil {
+		return a, err
+	}
+	switch {
+	case g.State() == StateBetting && a.Kind == ActionBet:
+		if a.Wager != 0 {
+			if err := g.SetWager(a.Wager); err != nil {
+				return a, err
+			}
+		}
+		return a, g.Hit()
+	case g.State() == StatePlaying && a.Kind == ActionHit:
+		return a, g.Hit()
+	case g.State() == StatePlaying && a.Kind == ActionCashOut:
+		g.CashOut()
+		return a, nil
+	case g.State() == StateBetting:
+		return a, fmt.Errorf("%w: %s before the round", ErrBadAction, a.Kind)
+	}
+	return a, fmt.Errorf("%w: %s during the round", ErrBadAction, a.Kind)
+}
+
+// PlayRound() plays a whole round by s, from the bet to collecting, as Step() does.
+func (g *Game) PlayRound(s Strategy, rng *rand.Rand, counts bool) error {
+	if g.State() != StateBetting {
+		return ErrRoundInProgress
+	}
+	for {
+		if _, err := g.Step(s, rng, counts); err != nil {
+			return err
+		}
+		if g.State() == StateBetting {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package tower
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrNoSeat = errors.New("tower: no such seat")
+	ErrNoBets = errors.New("tower: no one at the table has bet")
+)
+
+// Seat is a player at a Table.
+type Seat struct {
+	Name       string  `json:"name"`
+	Balance    int     `json:"balance"`
+	Wager      int     `json:"wager"`                  // the bet, kept from round to round, 0 sits rounds out
+	InRound    bool    `json:"in_round"`               // bet on the tower being dealt
+	Riding     bool    `json:"riding"`                 // in the round and hasn't cashed out or bust yet
+	Ready      bool    `json:"ready"`                  // asked for the next row, and waits on the others
+	CashOutRow int     `json:"cash_out_row,omitempty"` // the row cashed out at this round, 0 if none
+	Result     *Result `json:"result,omitempty"`       // how the seat's last round went, nil if it sat it out
+}
+
+// Table is a shared tower: several players bet on the same cards, and each decides on their own,
+// row by row, whether to cash out or keep riding. The next row is dealt once every player still
+// riding has asked for it, and a burn busts everyone still riding.
+//
+// The tower is a Game played at the minimum bet, so its multiplier is the tower's own. A seat's
+// multiplier, and its payout, are the tower's times its bet over WagerStep, the same as a Game
+// played alone at that bet.
+type Table struct {
+	g     Game
+	seats []Seat
+
+	onRoundEnd []func(Result)
+	onEvent    []func(Event)
+}
+
+// NewTable() creates an empty table. opts set up the tower the same as they would a Game, but
+// WithRoundEnd() hooks are called with every seat's result, and WithTableEvent() hooks are told
+// every seat's bets and cash outs apart, with Seat set. WithRoundLog() logs the tower as it was
+// played at the minimum bet, until the last player left it. WithBalance() does nothing, every
+// seat brings its own.
+func NewTable(opts ...Option) (*Table, error) {
+	g, err := NewGame(opts...)
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{g: g, onRoundEnd: g.onRoundEnd, onEvent: g.onEvent}
+	t.g.onRoundEnd, t.g.onEvent = nil, []func(Event){t.towerEvent}
+	t.g.wager = WagerStep
+	return t, nil
+}
+
+// Sit() seats a player with balance, and returns their seat. A player seated mid-round
+// waits for the next one.
+func (t *Table) Sit(name string, balance int) (int, error) {
+	if balance < 0 {
+		return 0, fmt.Errorf("tower: %s can't sit with a balance of %d", name, balance)
+	}
+	t.seats = append(t.seats, Seat{Name: name, Balance: balance, Wager: WagerStep})
+	return len(t.seats) - 1, nil
+}
+
+// Seats() returns every seat, in the order they were taken.
+func (t *Table) Seats() []Seat {
+	s := make([]Seat, len(t.seats))
+	for i, seat := range t.seats {
+		s[i] = seat
+		if seat.Result != nil {
+			r := *seat.Result
+			s[i].Result = &r
+		}
+	}
+	return s
+}
+
+// State() returns StateBetting between rounds and StatePlaying during them. A tower that bust
+// or was dealt to the end stays up in StateGameOver until the next Deal(), so it can be seen.
+func (t *Table) State() int {
+	return t.g.State()
+}
+
+// Bet() sets seat's bet, for every round until it's changed. A bet of 0 sits rounds out.
+// Bets are checked the same as SetWager() checks them.
+func (t *Table) Bet(seat, wager int) error {
+	s, err := t.seat(seat)
+	if err != nil {
+		return err
+	}
+	if t.State() == StatePlaying {
+		return ErrRoundInProgress
+	}
+	if wager != 0 {
+		if err := checkWagerStep(wager); err != nil {
+			return fmt.Errorf("%w: got %d", err, wager)
+		}
+		if wager > t.g.maxWager {
+			return fmt.Errorf("%w: got %d, maximum is %d", ErrWagerTooHigh, wager, t.g.maxWager)
+		}
+		if wager > s.Balance {
+			return fmt.Errorf("%w: bet is %d, balance is %d", ErrInsufficientBalance, wager, s.Balance)
+		}
+	}
+	s.Wager = wager
+	return nil
+}
+
+// Deal() starts a round: every seat that bet and can afford it pays, and the gate card and the
+// first row are dealt. The others sit the round out.
+func (t *Table) Deal() error {
+	if t.State() == StatePlaying {
+		return ErrRoundInProgress
+	}
+	if t.g.IsGameOver() {
+		t.g.Hit() // take the last tower down
+	}
+	in := 0
+	for i := range t.seats {
+		s := &t.seats[i]
+		s.Result, s.CashOutRow, s.Ready = nil, 0, false
+		s.InRound = s.Wager > 0 && s.Wager <= s.Balance
+		s.Riding = s.InRound
+		if s.InRound {
+			in++
+		}
+	}
+	if in == 0 {
+		return ErrNoBets
+	}
+
+	for i := range t.seats {
+		if s := &t.seats[i]; s.InRound {
+			s.Balance -= s.Wager
+			t.emit(Event{Kind: EventBet, Seat: s.Name, Wager: s.Wager})
+		}
+	}
+	t.g.balance = t.g.wager // the tower's own stake, never anyone's money
+	return t.g.Hit()
+}
+
+// Hit() asks for the next row for seat. It's dealt once everyone still riding has asked.
+func (t *Table) Hit(seat int) error {
+	s, err := t.rider(seat)
+	if err != nil {
+		return err
+	}
+	if s.Ready {
+		return fmt.Errorf("%w: %s is already waiting for the next row", ErrBadAction, s.Name)
+	}
+	s.Ready = true
+	t.advance()
+	return nil
+}
+
+// CashOut() pays seat for the last row dealt, times the multiplier and its bet over WagerStep.
+func (t *Table) CashOut(seat int) error {
+	s, err := t.rider(seat)
+	if err != nil {
+		return err
+	}
+	payout := t.g.cashOutValue() * s.Wager / WagerStep
+	s.Balance += payout
+	s.CashOutRow = t.g.lastDealtRow()
+	r := t.finish(s, t.g.lastDealtRow(), payout, false)
+	t.emit(Event{Kind: EventCashOut, Seat: s.Name, Row: r.Row, Result: &r})
+	t.advance()
+	return nil
+}
+
+// View() returns what seat can see: the shared tower, with its own balance, bet, multiplier and
+// what cashing out would pay it. counts adds the unseen cards.
+func (t *Table) View(seat int, counts bool) (View, error) {
+	s, err := t.seat(seat)
+	if err != nil {
+		return View{}, err
+	}
+	v := t.g.View(counts)
+	v.Balance, v.Wager = s.Balance, s.Wager
+	v.Multiplier = t.g.multiplier * max(s.Wager, WagerStep) / WagerStep
+	v.CashOut = 0
+	if s.Riding {
+		v.CashOut = t.g.cashOutValue() * s.Wager / WagerStep
+	}
+	return v, nil
+}
+
+// Seed() returns the seed the table's tower is shuffled from.
+func (t *Table) Seed() int64 {
+	return t.g.Seed()
+}
+
+// PrintTower() prints the shared tower.
+func (t *Table) PrintTower() {
+	t.g.PrintTower()
+}
+
+// SetOutput() sets where PrintTower() writes to. Defaults to os.Stdout.
+func (t *Table) SetOutput(w io.Writer) {
+	t.g.SetOutput(w)
+}
+
+// seat() returns seat.
+func (t *Table) seat(seat int) (*Seat, error) {
+	if seat < 0 || seat >= len(t.seats) {
+		return nil, fmt.Errorf("%w: %d", ErrNoSeat, seat)
+	}
+	return &t.seats[seat], nil
+}
+
+// rider() returns seat if it's still riding the tower.
+func (t *Table) rider(seat int) (*Seat, error) {
+	s, err := t.seat(seat)
+	if err != nil {
+		return nil, err
+	}
+	if t.State() != StatePlaying || !s.Riding {
+		return nil, fmt.Errorf("%w: %s isn't riding the tower", ErrBadAction, s.Name)
+	}
+	return s, nil
+}
+
+// advance() deals the next row once everyone riding is ready, and ends the round once no one is.
+func (t *Table) advance() {
+	riders := []*Seat{}
+	for i := range t.seats {
+		if s := &t.seats[i]; s.Riding {
+			if !s.Ready {
+				return
+			}
+			riders = append(riders, s)
+		}
+	}
+	if len(riders) == 0 {
+		t.g.CashOut() // the last player left the tower
+		return
+	}
+
+	for _, s := range riders {
+		s.Ready = false
+	}
+	t.g.Hit()
+	if !t.g.IsGameOver() {
+		return
+	}
+	row, jackpot := t.g.lastDealtRow(), t.g.isJackpot()
+	for _, s := range riders {
+		if t.g.IsBusted() {
+			t.finish(s, row, 0, false)
+			continue
+		}
+		payout := t.g.cashOutValue() * s.Wager / WagerStep
+		s.Balance += payout
+		s.CashOutRow = row
+		r := t.finish(s, row, payout, jackpot)
+		t.emit(Event{Kind: EventCashOut, Seat: s.Name, Row: row, Result: &r})
+	}
+}
+
+// finish() ends the round for s on row, having paid payout, and returns its result.
+func (t *Table) finish(s *Seat, row, payout int, jackpot bool) Result {
+	r := t.g.result(row, payout, jackpot)
+	r.Wager = s.Wager
+	r.Multiplier = t.g.multiplier * s.Wager / WagerStep
+	s.Riding, s.Result = false, &r
+	for _, f := range t.onRoundEnd {
+		f(r)
+	}
+	return r
+}
+
+// towerEvent() passes on what happens to the shared tower. Its own bets and cash outs, at the
+// minimum bet, aren't anyone's, and a bust's result is every rider's own.
+func (t *Table) towerEvent(e Event) {
+	switch e.Kind {
+	case EventBet, EventCashOut:
+		return
+	case EventBust:
+		e.Result = nil
+	}
+	t.emit(e)
+}
+
+// emit() calls the table event hooks with e.
+func (t *Table) emit(e Event) {
+	for _, f := range t.onEvent {
+		f(e)
+	}
+}
@@ -0,0 +1,219 @@
+package tower
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TournamentConfig configures Tournament().
+type TournamentConfig struct {
+	Deck          Deck
+	Rows          int
+	Wager         int // the bet at the start of every session
+	Seed          int64
+	Sessions      int  // sessions every entrant plays
+	SessionRounds int  // rounds in a session, unless the money runs out first
+	Bankroll      int  // money at the start of every session
+	Counts        bool // let the strategies see the unseen cards
+}
+
+// Entrant is a strategy playing in a tournament.
+type Entrant struct {
+	Name     string
+	Strategy Strategy
+}
+
+// Standing is how an entrant did over a tournament.
+type Standing struct {
+	Name     string
+	Rounds   int // rounds played, fewer than the tournament's if sessions ran out of money
+	Wagered  int64
+	Profit   int64   // payouts minus wagers
+	Variance float64 // of the profit per round
+	Ruin     float64 // share of sessions that ran out of money for the next bet
+	Growth   float64 // average bankroll at the end of a session, over the starting bankroll, minus 1
+}
+
+// StdDev() returns the standard deviation of the profit per round.
+func (s Standing) StdDev() float64 {
+	return math.Sqrt(s.Variance)
+}
+
+// Tournament() plays every entrant through the same sessions and returns their standings, best
+// profit first. Session i of every entrant is shuffled from the same seed, so they all get the
+// same cards, round for round. A session starts from cfg.Bankroll and cfg.Wager, and ends after
+// cfg.SessionRounds rounds, or when the entrant can't afford its next bet: that's ruin.
+// Entrants play at the same time, each on its own goroutine.
+func Tournament(cfg TournamentConfig, entrants []Entrant) ([]Standing, error) {
+	if cfg.Sessions < 1 || cfg.SessionRounds < 1 {
+		return nil, errors.New("tower: a tournament needs at least 1 session of 1 round")
+	}
+	if cfg.Bankroll < 1 {
+		return nil, errors.New("tower: a tournament needs a bankroll")
+	}
+	if len(entrants) == 0 {
+		return nil, errors.New("tower: a tournament needs at least 1 entrant")
+	}
+	// check the config once, before anyone plays
+	if _, err := newSessionGame(cfg, cfg.Seed); err != nil {
+		return nil, err
+	}
+
+	standings := make([]Standing, len(entrants))
+	errs := make([]error, len(entrants))
+	var wg sync.WaitGroup
+	for i, e := range entrants {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			standings[i], errs[i] = playEntrant(cfg, e)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("tower: %s: %w", entrants[i].Name, err)
+		}
+	}
+	SortStandings(standings, RankProfit)
+	return standings, nil
+}
+
+// newSessionGame() creates the game a session of a tournament is played on.
+func newSessionGame(cfg TournamentConfig, seed int64) (Game, error) {
+	g, err := NewGame(WithDeck(cfg.Deck), WithRows(cfg.Rows), WithMaxWager(max(cfg.Wager, DefaultMaxWager)),
+		WithBalance(cfg.Bankroll), WithSeed(seed))
+	if err != nil {
+		return Game{}, err
+	}
+	if err := g.SetWager(cfg.Wager); err != nil {
+		return Game{}, err
+	}
+	g.out = io.Discard
+	return g, nil
+}
+
+// playEntrant() plays every session of the tournament by e.
+func playEntrant(cfg TournamentConfig, e Entrant) (Standing, error) {
+	st := Standing{Name: e.Name}
+	var sumProfit, sumProfitSq, sumGrowth float64
+	ruined := 0
+	record := func(r Result) {
+		profit := int64(r.Payout - r.Wager)
+		st.Rounds++
+		st.Wagered += int64(r.Wager)
+		st.Profit += profit
+		sumProfit += float64(profit)
+		sumProfitSq += float64(profit * profit)
+	}
+
+	for s := 0; s < cfg.Sessions; s++ {
+		seed := ChunkSeed(cfg.Seed, s)
+		g, _ := newSessionGame(cfg, seed)
+		g.onRoundEnd = []func(Result){record}
+		rng := StrategyRand(seed)
+		for r := 0; r < cfg.SessionRounds; r++ {
+			err := g.PlayRound(e.Strategy, rng, cfg.Counts)
+			if errors.Is(err, ErrInsufficientBalance) {
+				ruined++
+				break
+			}
+			if err != nil {
+				return Standing{}, fmt.Errorf("session %d: %w", s+1, err)
+			}
+		}
+		sumGrowth += float64(g.Balance()) / float64(cfg.Bankroll)
+	}
+
+	n := float64(cfg.Sessions)
+	st.Ruin = float64(ruined) / n
+	st.Growth = sumGrowth/n - 1
+	if st.Rounds > 1 {
+		rounds := float64(st.Rounds)
+		mean := sumProfit / rounds
+		st.Variance = math.Max(sumProfitSq/rounds-mean*mean, 0) * rounds / (rounds - 1)
+	}
+	return st, nil
+}
+
+// The orders SortStandings() can rank by.
+const (
+	RankProfit   = "profit"   // most profit first
+	RankVariance = "variance" // steadiest first
+	RankRuin     = "ruin"     // least risk of ruin first
+	RankGrowth   = "growth"   // most bankroll growth first
+)
+
+// RankNames lists the orders SortStandings() can rank by.
+var RankNames = []string{RankProfit, RankVariance, RankRuin, RankGrowth}
+
+// SortStandings() ranks standings by one of RankNames. Ties are broken by the other orders,
+// in the order of RankNames, then by name.
+func SortStandings(standings []Standing, by string) error {
+	better := map[string]func(a, b Standing) int{
+		RankProfit:   func(a, b Standing) int { return cmp.Compare(b.Profit, a.Profit) },
+		RankVariance: func(a, b Standing) int { return cmp.Compare(a.Variance, b.Variance) },
+		RankRuin:     func(a, b Standing) int { return cmp.Compare(a.Ruin, b.Ruin) },
+		RankGrowth:   func(a, b Standing) int { return cmp.Compare(b.Growth, a.Growth) },
+	}
+	if better[by] == nil {
+		return fmt.Errorf("tower: can't rank by %q, want one of %s", by, strings.Join(RankNames, ", "))
+	}
+	order := append([]string{by}, RankNames...)
+	sort.SliceStable(standings, func(i, j int) bool {
+		for _, o := range order {
+			if c := better[o](standings[i], standings[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return standings[i].Name < standings[j].Name
+	})
+	return nil
+}
+
+// WriteLeaderboard() writes the standings as a table, in the order they're in.
+func WriteLeaderboard(w io.Writer, standings []Standing) error {
+	b := &strings.Builder{}
+	width := len("Strategy")
+	for _, s := range standings {
+		width = max(width, len(s.Name))
+	}
+	fmt.Fprintf(b, "%4s  %-*s  %8s  %9s  %10s  %8s  %7s  %8s\n", "Rank", width, "Strategy", "Rounds", "Profit", "Per round", "Std dev", "Ruin", "Growth")
+	for i, s := range standings {
+		fmt.Fprintf(b, "%4d  %-*s  %8d  %9d  %10.2f  %8.2f  %6.1f%%  %7.1f%%\n",
+			i+1, width, s.Name, s.Rounds, s.Profit, s.perRound(), s.StdDev(), 100*s.Ruin, 100*s.Growth)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteStandingsCSV() writes the standings as CSV, with a header row, in the order they're in.
+func WriteStandingsCSV(w io.Writer, standings []Standing) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"rank", "strategy", "rounds", "wagered", "profit", "profit_per_round", "variance", "std_dev", "risk_of_ruin", "growth"})
+	for i, s := range standings {
+		c.Write([]string{
+			fmt.Sprint(i + 1), s.Name, fmt.Sprint(s.Rounds), fmt.Sprint(s.Wagered), fmt.Sprint(s.Profit),
+			fmt.Sprintf("%.4f", s.perRound()), fmt.Sprintf("%.4f", s.Variance), fmt.Sprintf("%.4f", s.StdDev()),
+			fmt.Sprintf("%.4f", s.Ruin), fmt.Sprintf("%.4f", s.Growth),
+		})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// perRound() returns the average profit per round.
+func (s Standing) perRound() float64 {
+	if s.Rounds == 0 {
+		return 0
+	}
+	return float64(s.Profit) / float64(s.Rounds)
+}
@@ -0,0 +1,93 @@
+package tower
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// WagerStep is the smallest bet. Every bet is a multiple of it, and the
+	// starting multiplier is the bet / WagerStep.
+	WagerStep = 15
+
+	// DefaultMaxWager is the table maximum unless WithMaxWager() sets another.
+	DefaultMaxWager = 150
+)
+
+var (
+	ErrWagerStep           = errors.New("bet must be a multiple of 15")
+	ErrWagerTooLow         = errors.New("bet must be more than 0")
+	ErrWagerTooHigh        = errors.New("bet is over the table maximum")
+	ErrInsufficientBalance = errors.New("not enough money for that bet")
+	ErrRoundInProgress     = errors.New("can't change the bet during a round")
+)
+
+// WithMaxWager() sets the table maximum bet. It must be a positive multiple of WagerStep.
+func WithMaxWager(max int) Option {
+	return func(g *Game) error {
+		if err := checkWagerStep(max); err != nil {
+			return fmt.Errorf("tower: table maximum %d: %w", max, err)
+		}
+		g.maxWager = max
+		return nil
+	}
+}
+
+// GetWager() returns the current wager.
+func (g *Game) GetWager() int {
+	return g.wager
+}
+
+// MaxWager() returns the table maximum bet.
+func (g *Game) MaxWager() int {
+	return g.maxWager
+}
+
+// SetWager() sets the bet for the next round. Bets that aren't a positive multiple of 15,
+// that exceed the table maximum or the balance, or that are made mid-round are rejected.
+func (g *Game) SetWager(w int) error {
+	if g.State() != StateBetting {
+		return ErrRoundInProgress
+	}
+	if err := checkWagerStep(w); err != nil {
+		return fmt.Errorf("%w: got %d", err, w)
+	}
+	if w > g.maxWager {
+		return fmt.Errorf("%w: got %d, maximum is %d", ErrWagerTooHigh, w, g.maxWager)
+	}
+	if w > g.balance {
+		return fmt.Errorf("%w: bet is %d, balance is %d", ErrInsufficientBalance, w, g.balance)
+	}
+	g.wager = w
+	return nil
+}
+
+// RaiseWager() raises the bet by WagerStep.
+func (g *Game) RaiseWager() error {
+	return g.SetWager(g.wager + WagerStep)
+}
+
+// LowerWager() lowers the bet by WagerStep. It doesn't check the balance, so a bet left
+// over the balance by a loss can always be lowered back to one the player can cover;
+// the balance is checked when the bet is placed.
+func (g *Game) LowerWager() error {
+	if g.State() != StateBetting {
+		return ErrRoundInProgress
+	}
+	w := g.wager - WagerStep
+	if err := checkWagerStep(w); err != nil {
+		return fmt.Errorf("%w: got %d", err, w)
+	}
+	g.wager = w
+	return nil
+}
+
+func checkWagerStep(w int) error {
+	if w <= 0 {
+		return ErrWagerTooLow
+	}
+	if w%WagerStep != 0 {
+		return ErrWagerStep
+	}
+	return nil
+}